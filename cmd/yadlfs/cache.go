@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/alxarno/yadlfs/internal"
+	"github.com/urfave/cli/v2"
+)
+
+func cacheCommand() *cli.Command {
+	dirFlag := &cli.StringFlag{
+		Name:  "dir",
+		Usage: "cache folder, defaults to the configured one",
+	}
+
+	return &cli.Command{
+		Name:  "cache",
+		Usage: "manage the local object cache",
+		Subcommands: []*cli.Command{
+			{
+				Name:   "stats",
+				Usage:  "print the number of cached objects, their size and the hit rate",
+				Flags:  []cli.Flag{dirFlag},
+				Action: cacheStatsAction,
+			},
+			{
+				Name:  "prune",
+				Usage: "remove least recently used objects",
+				Flags: []cli.Flag{
+					dirFlag,
+					&cli.StringFlag{
+						Name:  "max-size",
+						Usage: "shrink the cache to the given size, e.g. 5GiB",
+					},
+					&cli.DurationFlag{
+						Name:  "older-than",
+						Usage: "remove objects not used for the given duration, e.g. 720h",
+					},
+				},
+				Action: cachePruneAction,
+			},
+			{
+				Name:   "verify",
+				Usage:  "re-hash cached objects and remove the corrupted ones",
+				Flags:  []cli.Flag{dirFlag},
+				Action: cacheVerifyAction,
+			},
+		},
+	}
+}
+
+// openCache resolves the cache folder from the --dir flag, the config or the
+// default location, in that order. Cache management doesn't need Yandex
+// credentials, so a missing config isn't an error here.
+func openCache(cCtx *cli.Context) (*internal.Cache, error) {
+	if dir := cCtx.String("dir"); dir != "" {
+		return internal.NewCache(dir, 0), nil
+	}
+
+	if config, err := internal.LoadConfig(); err == nil && config.CacheFolder != "" {
+		return internal.NewCache(config.CacheFolder, config.CacheMaxSize), nil
+	}
+
+	folder, err := internal.DefaultCacheFolder()
+	if err != nil {
+		return nil, err //nolint:wrapcheck // already wrapped
+	}
+
+	return internal.NewCache(folder, 0), nil
+}
+
+func cacheStatsAction(cCtx *cli.Context) error {
+	cache, err := openCache(cCtx)
+	if err != nil {
+		return err
+	}
+
+	stats, err := cache.Stats()
+	if err != nil {
+		return err //nolint:wrapcheck // already wrapped
+	}
+
+	fmt.Fprintf(cCtx.App.Writer, "objects:  %d\n", stats.Objects)
+	fmt.Fprintf(cCtx.App.Writer, "size:     %s\n", internal.FormatSize(stats.Bytes))
+	fmt.Fprintf(cCtx.App.Writer, "hits:     %d\n", stats.Hits)
+	fmt.Fprintf(cCtx.App.Writer, "misses:   %d\n", stats.Misses)
+	fmt.Fprintf(cCtx.App.Writer, "hit rate: %.1f%%\n", stats.HitRate()*100) //nolint:mnd // percents
+
+	return nil
+}
+
+func cachePruneAction(cCtx *cli.Context) error {
+	cache, err := openCache(cCtx)
+	if err != nil {
+		return err
+	}
+
+	var maxSize int64
+
+	if value := cCtx.String("max-size"); value != "" {
+		if maxSize, err = internal.ParseSize(value); err != nil {
+			return err
+		}
+	}
+
+	olderThan := cCtx.Duration("older-than")
+	if maxSize == 0 && olderThan == 0 {
+		return cli.Exit("either --max-size or --older-than is required", 1)
+	}
+
+	result, err := cache.Prune(maxSize, olderThan)
+	if err != nil {
+		return err //nolint:wrapcheck // already wrapped
+	}
+
+	fmt.Fprintf(cCtx.App.Writer, "removed %d objects, freed %s\n", result.Objects, internal.FormatSize(result.Bytes))
+
+	return nil
+}
+
+func cacheVerifyAction(cCtx *cli.Context) error {
+	cache, err := openCache(cCtx)
+	if err != nil {
+		return err
+	}
+
+	result, err := cache.Verify()
+	if err != nil {
+		return err //nolint:wrapcheck // already wrapped
+	}
+
+	for _, oid := range result.Corrupt {
+		fmt.Fprintf(cCtx.App.Writer, "removed corrupt object %s\n", oid)
+	}
+
+	fmt.Fprintf(cCtx.App.Writer, "verified %d objects, %d corrupt\n", result.Objects, len(result.Corrupt))
+
+	return nil
+}
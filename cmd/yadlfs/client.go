@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/alxarno/yadlfs/internal"
+	"github.com/alxarno/yadlfs/pkg"
+)
+
+func userAgent() string {
+	return "yadlfs/" + Version + " (" + CommitHash + ")"
+}
+
+// newHTTPClient creates the HTTP client described by the config. Its requests
+// keep the stall watchdog of the transfer they belong to in sync.
+func newHTTPClient(config *internal.Config) (*http.Client, error) {
+	client, err := pkg.NewHTTPClient(pkg.TransportConfig{
+		Proxy:                 config.HTTPProxy,
+		CABundle:              config.HTTPCABundle,
+		MaxIdleConns:          config.HTTPMaxIdleConns,
+		MaxConnsPerHost:       config.HTTPMaxConnsPerHost,
+		TLSHandshakeTimeout:   config.HTTPTLSHandshakeTimeout,
+		ResponseHeaderTimeout: config.HTTPResponseTimeout,
+	})
+	if err != nil {
+		return nil, err //nolint:wrapcheck // already wrapped
+	}
+
+	client.Transport = internal.NewWatchdogTransport(client.Transport)
+
+	return client, nil
+}
+
+// newYandexDiskClient creates a Yandex Disk client with the HTTP transport
+// described by the config.
+func newYandexDiskClient(config *internal.Config) (*pkg.YandexDiskClient, error) {
+	httpClient, err := newHTTPClient(config)
+	if err != nil {
+		return nil, err
+	}
+
+	client := pkg.NewYandexDiskClient(config.YandexDiskOAuthToken, config.YandexDiskProjectFolder)
+	client.HTTPClient = httpClient
+	client.UserAgent = userAgent()
+
+	return client, nil
+}
+
+// newS3Client creates a client of the S3 bucket described by the config.
+func newS3Client(config *internal.Config) (*pkg.S3Client, error) {
+	httpClient, err := newHTTPClient(config)
+	if err != nil {
+		return nil, err
+	}
+
+	client := pkg.NewS3Client(config.S3Bucket, config.S3Prefix, config.S3AccessKeyID, config.S3SecretAccessKey)
+	client.Endpoint = config.S3Endpoint
+	client.Region = config.S3Region
+	client.HTTPClient = httpClient
+	client.UserAgent = userAgent()
+
+	return client, nil
+}
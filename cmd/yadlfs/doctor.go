@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/alxarno/yadlfs/internal"
+	"github.com/alxarno/yadlfs/pkg"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	doctorProbe = ".yadlfs-doctor-probe"
+	tokenScopes = "cloud_api:disk.read, cloud_api:disk.write and cloud_api:disk.info"
+)
+
+func doctorCommand() *cli.Command {
+	return &cli.Command{
+		Name:   "doctor",
+		Usage:  "check the config, the Yandex Disk access and the git setup",
+		Action: doctorAction,
+	}
+}
+
+// doctorReport prints the check results as they come, a failed check
+// prints the way to fix it.
+type doctorReport struct {
+	w      io.Writer
+	failed bool
+}
+
+func (r *doctorReport) ok(name, detail string) {
+	fmt.Fprintf(r.w, "[ ok ] %s: %s\n", name, detail)
+}
+
+func (r *doctorReport) warn(name, detail, fix string) {
+	fmt.Fprintf(r.w, "[warn] %s: %s\n", name, detail)
+	fmt.Fprintf(r.w, "       fix: %s\n", fix)
+}
+
+func (r *doctorReport) fail(name, detail, fix string) {
+	r.failed = true
+
+	fmt.Fprintf(r.w, "[FAIL] %s: %s\n", name, detail)
+	fmt.Fprintf(r.w, "       fix: %s\n", fix)
+}
+
+func doctorAction(cCtx *cli.Context) error {
+	ctx := cCtx.Context
+	report := &doctorReport{w: cCtx.App.Writer}
+
+	if config := doctorConfig(report); config != nil {
+		doctorRemote(ctx, report, config)
+	}
+
+	doctorGit(ctx, report)
+
+	if report.failed {
+		return cli.Exit("some checks failed", 1)
+	}
+
+	return nil
+}
+
+func doctorConfig(report *doctorReport) *internal.Config {
+	config, err := internal.LoadConfig()
+	if err != nil {
+		report.fail(
+			"config", err.Error(),
+			"set YANDEX_DISK_OAUTH_TOKEN and YANDEX_DISK_PROJECT_FOLDER or create .yadlfs.yaml in the repository root",
+		)
+
+		return nil
+	}
+
+	settings, err := internal.DescribeConfig(config)
+	if err != nil {
+		report.fail("config", err.Error(), "fix the syntax of .yadlfs.yaml")
+
+		return nil
+	}
+
+	report.ok("config", "loaded")
+
+	table := tabwriter.NewWriter(report.w, 0, 0, 2, ' ', 0) //nolint:mnd // column padding
+	for _, setting := range settings {
+		if setting.Source != "default" {
+			fmt.Fprintf(table, "       %s\t%s\t%s\n", setting.Name, setting.Value, setting.Source)
+		}
+	}
+
+	table.Flush()
+	fmt.Fprintln(report.w, "       other settings use their defaults")
+
+	if _, err := internal.LoadEncryptionKey(config); err != nil {
+		report.fail("encryption", err.Error(), "check encryptionKeyFile or encryptionPassphrase")
+
+		return nil
+	}
+
+	if _, err := internal.ParseCompression(config.Compression); err != nil {
+		report.fail("compression", err.Error(), "set compression to none or gzip")
+
+		return nil
+	}
+
+	return config
+}
+
+func doctorRemote(ctx context.Context, report *doctorReport, config *internal.Config) {
+	client, err := newYandexDiskClient(config)
+	if err != nil {
+		report.fail("http", err.Error(), "check httpProxy and httpCaBundle")
+
+		return
+	}
+
+	info, err := client.Disk(ctx)
+
+	switch {
+	case errors.Is(err, pkg.ErrUnauthorized):
+		report.fail("token", err.Error(), "issue a new token at https://oauth.yandex.ru with the "+tokenScopes+" scopes")
+
+		return
+	case errors.Is(err, pkg.ErrForbidden):
+		report.fail("token", err.Error(), "issue a token for an application with the "+tokenScopes+" scopes")
+
+		return
+	case err != nil:
+		report.fail("token", err.Error(), "check the network connection and the proxy settings")
+
+		return
+	}
+
+	report.ok("token", fmt.Sprintf("valid, %s of %s free", internal.FormatSize(info.FreeSpace()), internal.FormatSize(info.TotalSpace)))
+
+	folder, err := client.Stat(ctx, "")
+
+	switch {
+	case errors.Is(err, pkg.ErrResourceNotFound):
+		report.fail(
+			"folder", config.YandexDiskProjectFolder+" doesn't exist",
+			"create the folder on Yandex Disk or fix yandexDiskProjectFolder",
+		)
+
+		return
+	case err != nil:
+		report.fail("folder", err.Error(), "check that the token may read "+config.YandexDiskProjectFolder)
+
+		return
+	case folder.Type != "dir":
+		report.fail("folder", config.YandexDiskProjectFolder+" is a file", "point yandexDiskProjectFolder to a folder")
+
+		return
+	}
+
+	report.ok("folder", config.YandexDiskProjectFolder+" exists")
+
+	if err := client.Upload(ctx, doctorProbe, strings.NewReader("yadlfs doctor"), true); err != nil {
+		report.fail("write", err.Error(), "issue a token with the cloud_api:disk.write scope")
+
+		return
+	}
+
+	if err := client.Delete(ctx, doctorProbe, true); err != nil {
+		report.warn("write", err.Error(), "remove "+client.ObjectPath(doctorProbe)+" by hand")
+
+		return
+	}
+
+	report.ok("write", "uploaded and deleted a probe object")
+}
+
+func doctorGit(ctx context.Context, report *doctorReport) {
+	version, err := internal.GitOutput(ctx, "lfs", "version")
+	if err != nil {
+		report.fail("git-lfs", err.Error(), "install git-lfs from https://git-lfs.com and run `git lfs install`")
+	} else {
+		report.ok("git-lfs", version)
+	}
+
+	if _, err := internal.GitOutput(ctx, "rev-parse", "--git-dir"); err != nil {
+		report.warn("git config", "not inside a git repository", "run doctor in the repository using yadlfs")
+
+		return
+	}
+
+	agent, _ := internal.GitOutput(ctx, "config", "--get", "lfs.standalonetransferagent")
+	if agent == "" {
+		report.fail(
+			"git config", "lfs.standalonetransferagent isn't set",
+			"git config lfs.standalonetransferagent yadlfs && git config lfs.customtransfer.yadlfs.path yadlfs",
+		)
+
+		return
+	}
+
+	path, _ := internal.GitOutput(ctx, "config", "--get", "lfs.customtransfer."+agent+".path")
+	if path == "" {
+		report.fail(
+			"git config", "lfs.customtransfer."+agent+".path isn't set",
+			"git config lfs.customtransfer."+agent+".path yadlfs",
+		)
+
+		return
+	}
+
+	if _, err := exec.LookPath(path); err != nil {
+		report.fail("git config", path+" isn't executable", "put yadlfs on PATH or set the full path to it")
+
+		return
+	}
+
+	report.ok("git config", "git-lfs runs "+path+" as the "+agent+" transfer agent")
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/alxarno/yadlfs/internal"
+	"github.com/urfave/cli/v2"
+)
+
+const defaultGCGracePeriod = 7 * 24 * time.Hour
+
+func gcCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "gc",
+		Usage: "delete remote objects which aren't referenced by any of the given refs",
+		Flags: []cli.Flag{
+			&cli.StringSliceFlag{
+				Name:  "ref",
+				Usage: "refs to keep objects for, all refs by default",
+			},
+			&cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: "only report objects which would be deleted",
+			},
+			&cli.DurationFlag{
+				Name:  "grace-period",
+				Usage: "keep unreferenced objects modified within this period, they may belong to a push in progress",
+				Value: defaultGCGracePeriod,
+			},
+			&cli.BoolFlag{
+				Name:  "permanently",
+				Usage: "delete objects permanently instead of moving them to the Yandex Disk trash",
+			},
+			&cli.BoolFlag{
+				Name:  "force",
+				Usage: "run even when the refs reference no objects, deleting every object out of the grace period",
+			},
+		},
+		Action: gcAction,
+	}
+}
+
+func gcAction(cCtx *cli.Context) error {
+	config, err := internal.LoadConfig()
+	if err != nil {
+		return err //nolint:wrapcheck // already wrapped
+	}
+
+	client, err := newYandexDiskClient(config)
+	if err != nil {
+		return err //nolint:wrapcheck // already wrapped
+	}
+
+	// a shallow history misses the objects of older commits
+	shallow, err := internal.IsShallowRepository(cCtx.Context)
+	if err != nil {
+		return err //nolint:wrapcheck // already wrapped
+	}
+
+	if shallow {
+		return cli.Exit("refusing to collect garbage in a shallow clone, run `git fetch --unshallow` first", 1)
+	}
+
+	pointers, err := internal.ListPointers(cCtx.Context, cCtx.StringSlice("ref"))
+	if err != nil {
+		return err //nolint:wrapcheck // already wrapped
+	}
+
+	if len(pointers) == 0 && !cCtx.Bool("force") {
+		return cli.Exit("no objects are referenced, refusing to delete them all without --force", 1)
+	}
+
+	referenced := make(map[string]bool, len(pointers))
+	for _, pointer := range pointers {
+		referenced[pointer.OID] = true
+	}
+
+	resources, err := client.List(cCtx.Context)
+	if err != nil {
+		return err //nolint:wrapcheck // already wrapped
+	}
+
+	dryRun := cCtx.Bool("dry-run")
+	deadline := time.Now().Add(-cCtx.Duration("grace-period"))
+
+	var (
+		deleted, kept, skipped int
+		freed                  int64
+	)
+
+	for _, resource := range resources {
+		// foreign files in the project folder are never touched
+		if !internal.IsOID(resource.Name) || referenced[resource.Name] {
+			kept++
+
+			continue
+		}
+
+		if resource.Modified.After(deadline) {
+			skipped++
+
+			fmt.Fprintf(cCtx.App.Writer, "skip %s (modified %s, within grace period)\n", resource.Name, resource.Modified.Format(time.RFC3339))
+
+			continue
+		}
+
+		if dryRun {
+			fmt.Fprintf(cCtx.App.Writer, "would delete %s (%s)\n", resource.Name, internal.FormatSize(resource.Size))
+		} else {
+			if err := client.Delete(cCtx.Context, resource.Name, cCtx.Bool("permanently")); err != nil {
+				return err //nolint:wrapcheck // already wrapped
+			}
+
+			fmt.Fprintf(cCtx.App.Writer, "delete %s (%s)\n", resource.Name, internal.FormatSize(resource.Size))
+		}
+
+		deleted++
+		freed += resource.Size
+	}
+
+	verb := "deleted"
+	if dryRun {
+		verb = "would delete"
+	}
+
+	fmt.Fprintf(
+		cCtx.App.Writer,
+		"%s %d objects (%s), kept %d, skipped %d within grace period\n",
+		verb, deleted, internal.FormatSize(freed), kept, skipped,
+	)
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/alxarno/yadlfs/internal"
+	"github.com/urfave/cli/v2"
+)
+
+func lsCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "ls",
+		Usage: "list objects stored in the remote project folder",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "json",
+				Usage: "print objects as JSON",
+			},
+		},
+		Action: lsAction,
+	}
+}
+
+type lsObject struct {
+	OID      string    `json:"oid"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+	SHA256   string    `json:"sha256"`
+}
+
+func lsAction(cCtx *cli.Context) error {
+	config, err := internal.LoadConfig()
+	if err != nil {
+		return err //nolint:wrapcheck // already wrapped
+	}
+
+	client, err := newYandexDiskClient(config)
+	if err != nil {
+		return err //nolint:wrapcheck // already wrapped
+	}
+
+	resources, err := client.List(cCtx.Context)
+	if err != nil {
+		return err //nolint:wrapcheck // already wrapped
+	}
+
+	objects := make([]lsObject, 0, len(resources))
+	for _, resource := range resources {
+		objects = append(objects, lsObject{resource.Name, resource.Size, resource.Modified, resource.SHA256})
+	}
+
+	if cCtx.Bool("json") {
+		encoder := json.NewEncoder(cCtx.App.Writer)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(objects) //nolint:wrapcheck // nothing to add
+	}
+
+	table := tabwriter.NewWriter(cCtx.App.Writer, 0, 0, 2, ' ', 0) //nolint:mnd // column padding
+	fmt.Fprintln(table, "OID\tSIZE\tMODIFIED\tSHA256")
+
+	for _, object := range objects {
+		fmt.Fprintf(table, "%s\t%d\t%s\t%s\n", object.OID, object.Size, object.Modified.Format(time.RFC3339), object.SHA256)
+	}
+
+	return table.Flush() //nolint:wrapcheck // nothing to add
+}
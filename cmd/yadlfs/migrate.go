@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/alxarno/yadlfs/internal"
+	"github.com/alxarno/yadlfs/pkg"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	defaultMigrateJobs = 4
+	migrateStateFolder = ".yadlfs"
+)
+
+func migrateCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "migrate",
+		Usage: "copy objects from one remote to another",
+		Flags: []cli.Flag{
+			&cli.PathFlag{
+				Name:     "from",
+				Usage:    "YAML config of the source remote, same format as .yadlfs.yaml with backend yandex or s3",
+				Required: true,
+			},
+			&cli.PathFlag{
+				Name:     "to",
+				Usage:    "YAML config of the destination remote, same format as .yadlfs.yaml with backend yandex or s3",
+				Required: true,
+			},
+			&cli.StringSliceFlag{
+				Name:  "ref",
+				Usage: "copy only objects referenced by these refs instead of the whole source folder",
+			},
+			&cli.PathFlag{
+				Name:  "state",
+				Usage: "file remembering copied objects, so an interrupted migration can be resumed " +
+					"(default: .yadlfs/migrate-<hash>.state, one per source and destination)",
+			},
+			&cli.IntFlag{
+				Name:  "jobs",
+				Usage: "number of objects copied concurrently",
+				Value: defaultMigrateJobs,
+			},
+		},
+		Action: migrateAction,
+	}
+}
+
+// migrationBackend is the storage of a remote, Yandex Disk or S3.
+type migrationBackend interface {
+	internal.Repository
+	List(ctx context.Context) ([]pkg.Resource, error)
+	Delete(ctx context.Context, filePath string, permanently bool) error
+}
+
+type migrationRemote struct {
+	config  *internal.Config
+	backend migrationBackend
+	// yandex is set for Yandex Disk remotes, which copy on the server side
+	yandex    *pkg.YandexDiskClient
+	warehouse internal.Repository
+}
+
+// sharesStorage reports whether objects can be copied on the server side:
+// both remotes must belong to the same Yandex Disk account and store objects
+// encoded with the same key. Compression may differ, since it's detected on
+// download.
+func (r *migrationRemote) sharesStorage(other *migrationRemote) bool {
+	return r.yandex != nil && other.yandex != nil &&
+		r.config.YandexDiskOAuthToken == other.config.YandexDiskOAuthToken &&
+		r.config.EncryptionKeyFile == other.config.EncryptionKeyFile &&
+		r.config.EncryptionPassphrase == other.config.EncryptionPassphrase
+}
+
+// copyObject copies an object to the destination remote, on the server
+// side when possible, reporting the bytes streamed through to progress.
+func (r *migrationRemote) copyObject(
+	ctx context.Context,
+	dst *migrationRemote,
+	serverSide bool,
+	oid string,
+	progress func(bytesSoFar, bytesSinceLast int64),
+) error {
+	if serverSide {
+		return dst.yandex.Copy(ctx, r.yandex.ObjectPath(oid), dst.yandex.ObjectPath(oid), true) //nolint:wrapcheck,lll // already wrapped
+	}
+
+	_, err := internal.MigrateObject(ctx, r.warehouse, dst.warehouse, oid, progress)
+
+	// the upload is aborted on a mismatch, the copy is removed in case the
+	// storage kept a part of it
+	if errors.Is(err, internal.ErrMigrationChecksum) {
+		deleteErr := dst.backend.Delete(ctx, oid, true)
+		if deleteErr != nil && !errors.Is(deleteErr, pkg.ErrResourceNotFound) {
+			return errors.Join(err, deleteErr)
+		}
+	}
+
+	return err //nolint:wrapcheck // already wrapped
+}
+
+func openMigrationRemote(path string) (*migrationRemote, error) {
+	config, err := internal.LoadConfigFile(path)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // already wrapped
+	}
+
+	remote := &migrationRemote{config: config}
+
+	switch config.Backend {
+	case internal.BackendYandex:
+		if remote.yandex, err = newYandexDiskClient(config); err != nil {
+			return nil, err
+		}
+
+		remote.backend = remote.yandex
+	case internal.BackendS3:
+		if remote.backend, err = newS3Client(config); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, cli.Exit(fmt.Sprintf("%s: unknown backend %q, expected yandex or s3", path, config.Backend), 1)
+	}
+
+	if remote.warehouse, err = internal.WrapRepository(config, remote.backend); err != nil {
+		return nil, err //nolint:wrapcheck // already wrapped
+	}
+
+	return remote, nil
+}
+
+// migrationObjects lists the objects to copy, their sizes are only used to
+// estimate the remaining time.
+func migrationObjects(cCtx *cli.Context, src *migrationRemote) ([]internal.Transfer, error) {
+	if refs := cCtx.StringSlice("ref"); len(refs) > 0 {
+		pointers, err := internal.ListPointers(cCtx.Context, refs)
+		if err != nil {
+			return nil, err //nolint:wrapcheck // already wrapped
+		}
+
+		objects := make([]internal.Transfer, 0, len(pointers))
+		for _, pointer := range pointers {
+			objects = append(objects, internal.Transfer{OID: pointer.OID, Size: pointer.Size})
+		}
+
+		return objects, nil
+	}
+
+	resources, err := src.backend.List(cCtx.Context)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // already wrapped
+	}
+
+	objects := make([]internal.Transfer, 0, len(resources))
+	for _, resource := range resources {
+		if internal.IsOID(resource.Name) {
+			objects = append(objects, internal.Transfer{OID: resource.Name, Size: resource.Size})
+		}
+	}
+
+	return objects, nil
+}
+
+func migrateAction(cCtx *cli.Context) error {
+	src, err := openMigrationRemote(cCtx.Path("from"))
+	if err != nil {
+		return err
+	}
+
+	dst, err := openMigrationRemote(cCtx.Path("to"))
+	if err != nil {
+		return err
+	}
+
+	objects, err := migrationObjects(cCtx, src)
+	if err != nil {
+		return err
+	}
+
+	present, err := dst.backend.List(cCtx.Context)
+	if err != nil {
+		return err //nolint:wrapcheck // already wrapped
+	}
+
+	existing := make(map[string]pkg.Resource, len(present))
+	for _, resource := range present {
+		existing[resource.Name] = resource
+	}
+
+	route := internal.MigrationRoute(src.config, dst.config)
+
+	statePath := cCtx.Path("state")
+	if statePath == "" {
+		statePath = internal.DefaultMigrationStatePath(migrateStateFolder, route)
+	}
+
+	state, err := internal.OpenMigrationState(statePath, route)
+	if err != nil {
+		return err //nolint:wrapcheck // already wrapped
+	}
+	defer state.Close()
+
+	// objects found on the destination may be left by an interrupted run,
+	// only the state file or a matching sha256 proves them complete
+	for _, object := range objects {
+		if resource, ok := existing[object.OID]; ok && resource.SHA256 == object.OID && !state.Done(object.OID) {
+			if err := state.MarkDone(object.OID); err != nil {
+				return err //nolint:wrapcheck // already wrapped
+			}
+		}
+	}
+
+	pending := slices.DeleteFunc(objects, func(object internal.Transfer) bool {
+		return state.Done(object.OID)
+	})
+	skipped := len(objects) - len(pending)
+
+	serverSide := src.sharesStorage(dst)
+	if serverSide {
+		fmt.Fprintln(cCtx.App.Writer, "remotes share the account, copying on the server side")
+	}
+
+	progress := internal.NewTerminalProgress(cCtx.App.Writer, internal.IsTerminal(cCtx.App.Writer), pending)
+
+	group, ctx := errgroup.WithContext(cCtx.Context)
+	group.SetLimit(max(cCtx.Int("jobs"), 1))
+
+	for _, object := range pending {
+		group.Go(func() error {
+			err := src.copyObject(ctx, dst, serverSide, object.OID, func(bytesSoFar, bytesSinceLast int64) {
+				progress.Message(internal.ProgressMessage{
+					OID:            object.OID,
+					BytesSoFar:     bytesSoFar,
+					BytesSinceLast: bytesSinceLast,
+				})
+			})
+			if err != nil {
+				progress.Message(internal.CompleteErrorMessage{
+					OID:   object.OID,
+					Error: internal.CompleteErrorMessageContent{Code: internal.ErrorCodeGeneric, Message: err.Error()},
+				})
+
+				return nil
+			}
+
+			progress.Message(internal.CompleteMessage{OID: object.OID})
+
+			return state.MarkDone(object.OID) //nolint:wrapcheck // already wrapped
+		})
+	}
+
+	if err := group.Wait(); err != nil {
+		return err //nolint:wrapcheck // already wrapped
+	}
+
+	_, failed := progress.Finish("copied")
+	fmt.Fprintf(cCtx.App.Writer, "skipped %d already migrated\n", skipped)
+
+	if failed > 0 {
+		return cli.Exit("", 1)
+	}
+
+	return nil
+}
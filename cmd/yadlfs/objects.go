@@ -0,0 +1,411 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/alxarno/yadlfs/internal"
+	"github.com/urfave/cli/v2"
+)
+
+var ErrInvalidObjectList = errors.New("invalid object list")
+
+const defaultObjectJobs = 4
+
+func pushObjectsCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "push-objects",
+		Usage: "upload objects from the local git-lfs store without git-lfs",
+		Flags: append(objectsFlags(), &cli.BoolFlag{
+			Name:  "force",
+			Usage: "upload objects the remote already has",
+		}),
+		Action: pushObjectsAction,
+	}
+}
+
+func fetchObjectsCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "fetch-objects",
+		Usage: "download objects into the local git-lfs store without git-lfs",
+		Flags: append(
+			objectsFlags(),
+			&cli.StringSliceFlag{
+				Name:  "ref",
+				Usage: "fetch objects referenced by these refs instead of all refs",
+			},
+			&cli.BoolFlag{
+				Name:  "force",
+				Usage: "download objects the local store already has",
+			},
+		),
+		Action: fetchObjectsAction,
+	}
+}
+
+func objectsFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "stdin",
+			Usage: "read objects from stdin, one `oid [size]` per line",
+		},
+		&cli.IntFlag{
+			Name:  "jobs",
+			Usage: "number of objects transferred concurrently",
+			Value: defaultObjectJobs,
+		},
+	}
+}
+
+func pushObjectsAction(cCtx *cli.Context) error {
+	ctx := cCtx.Context
+
+	objectsDir, err := lfsObjectsDir(ctx)
+	if err != nil {
+		return err
+	}
+
+	var transfers []internal.Transfer
+
+	if cCtx.Bool("stdin") {
+		pointers, err := readObjectList(cCtx.App.Reader)
+		if err != nil {
+			return err
+		}
+
+		for _, pointer := range pointers {
+			path := lfsObjectPath(objectsDir, pointer.OID)
+
+			info, err := os.Stat(path)
+			if err != nil {
+				return fmt.Errorf("object %s isn't in the local store: %w", pointer.OID, err)
+			}
+
+			transfers = append(transfers, internal.Transfer{OID: pointer.OID, Size: info.Size(), Path: path})
+		}
+	} else if transfers, err = localObjects(objectsDir); err != nil {
+		return err
+	}
+
+	pipeline, closeLog, err := openTransferPipeline(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeLog.Close()
+
+	if !cCtx.Bool("force") {
+		present, err := pipeline.client.List(ctx)
+		if err != nil {
+			return err //nolint:wrapcheck // already wrapped
+		}
+
+		names := make(map[string]bool, len(present))
+		for _, resource := range present {
+			names[resource.Name] = true
+		}
+
+		transfers = slices.DeleteFunc(transfers, func(transfer internal.Transfer) bool { return names[transfer.OID] })
+	}
+
+	progress := newObjectsProgress(cCtx, transfers)
+
+	err = transferObjects(ctx, pipeline, internal.OperationNameUpload, transfers, cCtx.Int("jobs"), progress.Message)
+	if err != nil {
+		return err
+	}
+
+	return finishObjectsProgress(progress, "pushed")
+}
+
+func fetchObjectsAction(cCtx *cli.Context) error {
+	ctx := cCtx.Context
+
+	objectsDir, err := lfsObjectsDir(ctx)
+	if err != nil {
+		return err
+	}
+
+	var pointers []internal.Pointer
+
+	if cCtx.Bool("stdin") {
+		pointers, err = readObjectList(cCtx.App.Reader)
+	} else {
+		pointers, err = internal.ListPointers(ctx, cCtx.StringSlice("ref"))
+	}
+
+	if err != nil {
+		return err //nolint:wrapcheck // already wrapped
+	}
+
+	transfers := make([]internal.Transfer, 0, len(pointers))
+
+	for _, pointer := range pointers {
+		if _, err := os.Stat(lfsObjectPath(objectsDir, pointer.OID)); err == nil && !cCtx.Bool("force") {
+			continue
+		}
+
+		transfers = append(transfers, internal.Transfer{OID: pointer.OID, Size: pointer.Size})
+	}
+
+	pipeline, closeLog, err := openTransferPipeline(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeLog.Close()
+
+	progress := newObjectsProgress(cCtx, transfers)
+
+	// downloads land in the temp folder, git-lfs would move them to the store
+	store := func(msg internal.DialMessage) {
+		if complete, ok := msg.(internal.CompleteMessage); ok && complete.Path != nil {
+			if err := storeObject(objectsDir, complete.OID, *complete.Path); err != nil {
+				msg = internal.CompleteErrorMessage{
+					OID:   complete.OID,
+					Error: internal.CompleteErrorMessageContent{Code: internal.ErrorCodeGeneric, Message: err.Error()},
+				}
+			}
+		}
+
+		progress.Message(msg)
+	}
+
+	if err := transferObjects(ctx, pipeline, internal.OperationNameDownload, transfers, cCtx.Int("jobs"), store); err != nil {
+		return err
+	}
+
+	return finishObjectsProgress(progress, "fetched")
+}
+
+func openTransferPipeline(ctx context.Context) (*transferPipeline, io.Closer, error) {
+	config, err := internal.LoadConfig()
+	if err != nil {
+		return nil, nil, err //nolint:wrapcheck // already wrapped
+	}
+
+	logger, logFile, err := internal.NewLogger(config)
+	if err != nil {
+		return nil, nil, err //nolint:wrapcheck // already wrapped
+	}
+
+	pipeline, err := newTransferPipeline(ctx, config, logger)
+	if err != nil {
+		logFile.Close()
+
+		return nil, nil, err
+	}
+
+	return pipeline, logFile, nil
+}
+
+// transferObjects runs the transfers through the controller git-lfs talks
+// to. The protocol lines are generated here and the replies go to report
+// instead of the JSON dial.
+func transferObjects(
+	ctx context.Context,
+	pipeline *transferPipeline,
+	operation internal.OperationName,
+	transfers []internal.Transfer,
+	jobs int,
+	report func(internal.DialMessage),
+) error {
+	messages := make(chan internal.DialMessage)
+	controller := internal.NewController(pipeline.warehouse, pipeline.folder, messages, pipeline.options...)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	input, inputWriter := io.Pipe()
+	defer input.Close()
+
+	go func() {
+		inputWriter.CloseWithError(writeSession(inputWriter, operation, transfers, jobs))
+	}()
+
+	reported := make(chan struct{})
+
+	go func() {
+		defer close(reported)
+
+		for {
+			select {
+			case msg := <-messages:
+				report(msg)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	err := internal.NewDispatcher(input, controller).ListenAndServe(ctx)
+
+	// terminate waits for all transfers, so every reply has been reported
+	cancel()
+	<-reported
+
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err //nolint:wrapcheck // already wrapped
+	}
+
+	return nil
+}
+
+func writeSession(w io.Writer, operation internal.OperationName, transfers []internal.Transfer, jobs int) error {
+	encoder := json.NewEncoder(w)
+
+	session := internal.Init{
+		Event:               internal.EventNameInit,
+		Operation:           operation,
+		Remote:              "yadlfs",
+		Concurrent:          true,
+		ConcurrentTransfers: int64(jobs),
+	}
+
+	if err := encoder.Encode(session); err != nil {
+		return fmt.Errorf("failed to write session: %w", err)
+	}
+
+	event := internal.EventNameUpload
+	if operation == internal.OperationNameDownload {
+		event = internal.EventNameDownload
+	}
+
+	for _, transfer := range transfers {
+		transfer.Event = event
+
+		if err := encoder.Encode(transfer); err != nil {
+			return fmt.Errorf("failed to write session: %w", err)
+		}
+	}
+
+	if err := encoder.Encode(map[string]internal.EventName{"event": internal.EventNameTerminate}); err != nil {
+		return fmt.Errorf("failed to write session: %w", err)
+	}
+
+	return nil
+}
+
+// lfsObjectsDir returns the git-lfs object store of the current repository.
+func lfsObjectsDir(ctx context.Context) (string, error) {
+	gitDir, err := internal.GitCommonDir(ctx)
+	if err != nil {
+		return "", err //nolint:wrapcheck // already wrapped
+	}
+
+	return filepath.Join(gitDir, "lfs", "objects"), nil
+}
+
+func lfsObjectPath(objectsDir, oid string) string {
+	return filepath.Join(objectsDir, oid[0:2], oid[2:4], oid)
+}
+
+// localObjects lists the objects of the local store.
+func localObjects(objectsDir string) ([]internal.Transfer, error) {
+	var transfers []internal.Transfer
+
+	err := filepath.WalkDir(objectsDir, func(path string, entry fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) && path == objectsDir {
+			return fs.SkipAll
+		} else if err != nil {
+			return err
+		}
+
+		if entry.IsDir() || !internal.IsOID(entry.Name()) {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err //nolint:wrapcheck // wrapped below
+		}
+
+		transfers = append(transfers, internal.Transfer{OID: entry.Name(), Size: info.Size(), Path: path})
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list local objects: %w", err)
+	}
+
+	return transfers, nil
+}
+
+// storeObject moves a downloaded object from the temp path into the local
+// store. git-lfs verifies the objects it stores, without it the object is
+// checked here, since git-lfs trusts its store.
+func storeObject(objectsDir, oid, tmpPath string) error {
+	if err := internal.VerifyObject(tmpPath, oid); err != nil {
+		os.Remove(tmpPath)
+
+		return fmt.Errorf("failed to store object: %w", err)
+	}
+
+	path := lfsObjectPath(objectsDir, oid)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil { //nolint:mnd // rwxr-xr-x
+		return fmt.Errorf("failed to store object: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to store object: %w", err)
+	}
+
+	return nil
+}
+
+// readObjectList reads `oid [size]` lines, empty lines are skipped.
+func readObjectList(r io.Reader) ([]internal.Pointer, error) {
+	var pointers []internal.Pointer
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		if !internal.IsOID(fields[0]) || len(fields) > 2 { //nolint:mnd // oid, size
+			return nil, fmt.Errorf("%w: %q", ErrInvalidObjectList, scanner.Text())
+		}
+
+		pointer := internal.Pointer{OID: fields[0]}
+
+		if len(fields) == 2 { //nolint:mnd // oid, size
+			size, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %q", ErrInvalidObjectList, scanner.Text())
+			}
+
+			pointer.Size = size
+		}
+
+		pointers = append(pointers, pointer)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidObjectList, err)
+	}
+
+	return pointers, nil
+}
+
+func newObjectsProgress(cCtx *cli.Context, transfers []internal.Transfer) *internal.TerminalProgress {
+	return internal.NewTerminalProgress(cCtx.App.Writer, internal.IsTerminal(cCtx.App.Writer), transfers)
+}
+
+func finishObjectsProgress(progress *internal.TerminalProgress, verb string) error {
+	if _, failed := progress.Finish(verb); failed > 0 {
+		return cli.Exit("", 1)
+	}
+
+	return nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/alxarno/yadlfs/internal"
+	"github.com/alxarno/yadlfs/pkg"
+)
+
+var ErrUnsupportedBackend = errors.New("unsupported backend")
+
+// transferPipeline is the storage stack and the controller options shared by
+// the agent and the standalone transfer commands.
+type transferPipeline struct {
+	client    *pkg.YandexDiskClient
+	warehouse internal.Repository
+	folder    string
+	options   []internal.ControllerOption
+}
+
+func newTransferPipeline(ctx context.Context, config *internal.Config, logger *slog.Logger) (*transferPipeline, error) {
+	if config.Backend != internal.BackendYandex {
+		return nil, fmt.Errorf(
+			"%w: %s, transfers use Yandex Disk, other backends are for migrate",
+			ErrUnsupportedBackend, config.Backend,
+		)
+	}
+
+	folder, err := internal.LoadTmpFolder(ctx, config)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // already wrapped
+	}
+
+	if config.TmpMaxAge > 0 {
+		removed, err := internal.CleanTmpFolder(folder, config.TmpMaxAge)
+		if err != nil {
+			logger.Warn("failed to clean temp folder", "folder", folder, "error", err)
+		} else if removed > 0 {
+			logger.Info("removed orphaned temp files", "folder", folder, "files", removed)
+		}
+	}
+
+	cache, err := internal.LoadCache(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load cache: %w", err)
+	}
+
+	client, err := newYandexDiskClient(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up http client: %w", err)
+	}
+
+	client.Logger = logger
+
+	// chunked uploads are opt-in, since the storage may not honour
+	// Content-Range, and resuming needs the same stream on every attempt,
+	// encrypted streams differ
+	var backend internal.Repository = client
+	if config.UploadChunkSize > 0 && config.StoresPlainObjects() {
+		backend = internal.NewResumableRepository(client, internal.UploadStateFolder(folder), config.UploadChunkSize)
+	}
+
+	warehouse, err := internal.WrapRepository(config, backend)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up repository: %w", err)
+	}
+
+	options := []internal.ControllerOption{
+		internal.WithLogger(logger),
+		internal.WithTransferTimeouts(config.TransferIdleTimeout, config.TransferTimeout),
+		internal.WithQuota(client),
+	}
+
+	if config.StoresPlainObjects() {
+		options = append(options, internal.WithRangedDownloads(client, config.DownloadParts, config.DownloadPartsMinSize))
+	}
+
+	if cache != nil {
+		options = append(options, internal.WithCache(cache))
+	}
+
+	if config.StatsFile != "" {
+		options = append(options, internal.WithStatsFile(config.StatsFile))
+	}
+
+	if config.MetricsFile != "" {
+		metrics := internal.NewMetrics()
+		client.Observer = metrics
+		options = append(options, internal.WithMetricsFile(config.MetricsFile, metrics))
+	}
+
+	return &transferPipeline{client, warehouse, folder, options}, nil
+}
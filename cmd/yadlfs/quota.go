@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/alxarno/yadlfs/internal"
+	"github.com/urfave/cli/v2"
+)
+
+func quotaCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "quota",
+		Usage: "print the disk space of the configured Yandex Disk account",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "json",
+				Usage: "print the space as JSON",
+			},
+		},
+		Action: quotaAction,
+	}
+}
+
+type quotaUsage struct {
+	Total int64 `json:"total"`
+	Used  int64 `json:"used"`
+	Trash int64 `json:"trash"`
+	Free  int64 `json:"free"`
+}
+
+func quotaAction(cCtx *cli.Context) error {
+	config, err := internal.LoadConfig()
+	if err != nil {
+		return err //nolint:wrapcheck // already wrapped
+	}
+
+	client, err := newYandexDiskClient(config)
+	if err != nil {
+		return err //nolint:wrapcheck // already wrapped
+	}
+
+	info, err := client.Disk(cCtx.Context)
+	if err != nil {
+		return err //nolint:wrapcheck // already wrapped
+	}
+
+	usage := quotaUsage{info.TotalSpace, info.UsedSpace, info.TrashSize, info.FreeSpace()}
+
+	if cCtx.Bool("json") {
+		encoder := json.NewEncoder(cCtx.App.Writer)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(usage) //nolint:wrapcheck // nothing to add
+	}
+
+	used := 0.0
+	if usage.Total > 0 {
+		used = float64(usage.Used) / float64(usage.Total) * 100 //nolint:mnd // percent
+	}
+
+	table := tabwriter.NewWriter(cCtx.App.Writer, 0, 0, 2, ' ', 0) //nolint:mnd // column padding
+	fmt.Fprintf(table, "Total:\t%s\n", internal.FormatSize(usage.Total))
+	fmt.Fprintf(table, "Used:\t%s (%.1f%%)\n", internal.FormatSize(usage.Used), used)
+	fmt.Fprintf(table, "Trash:\t%s\n", internal.FormatSize(usage.Trash))
+	fmt.Fprintf(table, "Free:\t%s\n", internal.FormatSize(usage.Free))
+
+	return table.Flush() //nolint:wrapcheck // nothing to add
+}
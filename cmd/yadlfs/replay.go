@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/alxarno/yadlfs/internal"
+	"github.com/urfave/cli/v2"
+)
+
+// replayTmpFolder keeps replayed downloads out of the git-lfs temp folder.
+const replayTmpFolder = ".yadlfs/tmp"
+
+func replayCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "replay",
+		Usage:     "feed a session recorded with --trace into the agent to reproduce it",
+		ArgsUsage: "<trace file>",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "backend",
+				Usage: "storage to replay against: filesystem or yandex",
+				Value: "filesystem",
+			},
+			&cli.PathFlag{
+				Name:  "root",
+				Usage: "folder of the filesystem backend",
+				Value: ".yadlfs/replay",
+			},
+		},
+		Action: replayAction,
+	}
+}
+
+func replayBackend(cCtx *cli.Context) (internal.Repository, error) {
+	switch backend := cCtx.String("backend"); backend {
+	case "filesystem":
+		return internal.NewFilesystemRepository(cCtx.Path("root")), nil
+	case "yandex":
+		config, err := internal.LoadConfig()
+		if err != nil {
+			return nil, err //nolint:wrapcheck // already wrapped
+		}
+
+		client, err := newYandexDiskClient(config)
+		if err != nil {
+			return nil, err //nolint:wrapcheck // already wrapped
+		}
+
+		return internal.WrapRepository(config, client) //nolint:wrapcheck // already wrapped
+	default:
+		return nil, cli.Exit("unknown backend "+backend, 1)
+	}
+}
+
+func replayAction(cCtx *cli.Context) error {
+	if cCtx.NArg() != 1 {
+		return cli.Exit("expected a single trace file", 1)
+	}
+
+	traceFile, err := os.Open(cCtx.Args().First())
+	if err != nil {
+		return fmt.Errorf("failed to open trace: %w", err)
+	}
+	defer traceFile.Close()
+
+	records, err := internal.ReadTrace(traceFile)
+	if err != nil {
+		return err //nolint:wrapcheck // already wrapped
+	}
+
+	warehouse, err := replayBackend(cCtx)
+	if err != nil {
+		return err
+	}
+
+	return serve(cCtx.Context, warehouse, replayTmpFolder, internal.ReplayInput(records), cCtx.App.Writer)
+}
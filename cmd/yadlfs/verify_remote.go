@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/alxarno/yadlfs/internal"
+	"github.com/alxarno/yadlfs/pkg"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/sync/errgroup"
+)
+
+const defaultVerifyJobs = 8
+
+func verifyRemoteCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "verify-remote",
+		Usage: "check that every object referenced by the given refs is stored intact on Yandex Disk",
+		Flags: []cli.Flag{
+			&cli.StringSliceFlag{
+				Name:  "ref",
+				Usage: "refs to check objects for, all refs by default",
+			},
+			&cli.IntFlag{
+				Name:  "jobs",
+				Usage: "number of concurrent metadata requests",
+				Value: defaultVerifyJobs,
+			},
+		},
+		Action: verifyRemoteAction,
+	}
+}
+
+func verifyRemoteAction(cCtx *cli.Context) error {
+	config, err := internal.LoadConfig()
+	if err != nil {
+		return err //nolint:wrapcheck // already wrapped
+	}
+
+	client, err := newYandexDiskClient(config)
+	if err != nil {
+		return err //nolint:wrapcheck // already wrapped
+	}
+
+	pointers, err := internal.ListPointers(cCtx.Context, cCtx.StringSlice("ref"))
+	if err != nil {
+		return err //nolint:wrapcheck // already wrapped
+	}
+
+	// encrypted or compressed objects are stored with a different size and
+	// hash, so only their presence can be checked
+	existenceOnly := !config.StoresPlainObjects()
+	if existenceOnly {
+		fmt.Fprintln(cCtx.App.ErrWriter, "objects are encrypted or compressed, checking presence only")
+	}
+
+	var (
+		mu       sync.Mutex
+		problems int
+	)
+
+	report := func(format string, args ...any) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		problems++
+
+		fmt.Fprintf(cCtx.App.Writer, format+"\n", args...)
+	}
+
+	group, ctx := errgroup.WithContext(cCtx.Context)
+	group.SetLimit(max(cCtx.Int("jobs"), 1))
+
+	for _, pointer := range pointers {
+		group.Go(func() error {
+			resource, err := client.Stat(ctx, pointer.OID)
+			if errors.Is(err, pkg.ErrResourceNotFound) {
+				report("missing  %s", pointer.OID)
+
+				return nil
+			} else if err != nil {
+				return err //nolint:wrapcheck // already wrapped
+			}
+
+			switch {
+			case existenceOnly:
+			case resource.Size != pointer.Size:
+				report("size     %s: expected %d, got %d", pointer.OID, pointer.Size, resource.Size)
+			case resource.SHA256 != "" && resource.SHA256 != pointer.OID:
+				report("sha256   %s: got %s", pointer.OID, resource.SHA256)
+			}
+
+			return nil
+		})
+	}
+
+	if err := group.Wait(); err != nil {
+		return err //nolint:wrapcheck // already wrapped
+	}
+
+	fmt.Fprintf(cCtx.App.Writer, "checked %d objects, %d problems\n", len(pointers), problems)
+
+	if problems > 0 {
+		return cli.Exit("", 1)
+	}
+
+	return nil
+}
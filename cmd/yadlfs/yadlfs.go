@@ -2,12 +2,14 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"sync"
 
 	"github.com/alxarno/yadlfs/internal"
-	"github.com/alxarno/yadlfs/pkg"
 	"github.com/urfave/cli/v2"
 )
 
@@ -18,29 +20,91 @@ var (
 	BuildTimestamp = "n/a"
 )
 
-func action(_ *cli.Context) error {
+// serve runs the git-lfs custom transfer protocol over stdin/stdout until
+// git-lfs terminates the session.
+func serve(
+	ctx context.Context,
+	warehouse internal.Repository,
+	folder string,
+	stdin io.Reader,
+	stdout io.Writer,
+	opts ...internal.ControllerOption,
+) error {
+	messages := make(chan internal.DialMessage)
+
+	dial := internal.NewDial(stdout, messages)
+	controller := internal.NewController(warehouse, folder, messages, opts...)
+	dispatcher := internal.NewDispatcher(stdin, controller)
+
+	ctx, cancelFunc := context.WithCancel(ctx)
+	wg := sync.WaitGroup{}
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		dial.ListenAndServe(ctx)
+	}()
+
+	err := dispatcher.ListenAndServe(ctx)
+
+	// let dial finish writing the last message before returning
+	cancelFunc()
+	wg.Wait()
+
+	// terminate message stops the dispatcher with io.EOF
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err //nolint:wrapcheck // already wrapped
+	}
+
+	return nil
+}
+
+func action(cCtx *cli.Context) error {
 	config, err := internal.LoadConfig()
 	if err != nil {
 		panic(err)
 	}
 
-	tmpFolder := ".yadlfs"
-	messages := make(chan internal.DialMessage)
+	logger, logFile, err := internal.NewLogger(config)
+	if err != nil {
+		panic(err)
+	}
+	defer logFile.Close()
+
+	logger.Info("agent started", "version", Version, "commit", CommitHash)
+
+	pipeline, err := newTransferPipeline(cCtx.Context, config, logger)
+	if err != nil {
+		logger.Error("failed to set up transfers", "error", err)
+		panic(err)
+	}
 
-	yandexDiskClient := pkg.NewYandexDiskClient(config.YandexDiskOAuthToken, config.YandexDiskProjectFolder)
-	dial := internal.NewDial(os.Stdout, messages)
-	controller := internal.NewController(yandexDiskClient, tmpFolder, messages)
-	dispatcher := internal.NewDispatcher(os.Stdin, controller)
+	var (
+		stdin  io.Reader = os.Stdin
+		stdout io.Writer = os.Stdout
+	)
 
-	ctx, cancelFunc := context.WithCancel(context.Background())
-	defer cancelFunc()
+	if tracePath := cCtx.Path("trace"); tracePath != "" {
+		traceFile, err := os.OpenFile(tracePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600) //nolint:mnd // rw-------
+		if err != nil {
+			logger.Error("failed to open trace file", "error", err)
+			panic(err)
+		}
+		defer traceFile.Close()
 
-	go dial.ListenAndServe(ctx)
+		tracer := internal.NewTracer(traceFile)
+		stdin, stdout = tracer.Reader(stdin), tracer.Writer(stdout)
+	}
 
-	if err := dispatcher.ListenAndServe(ctx); err != nil {
+	if err := serve(cCtx.Context, pipeline.warehouse, pipeline.folder, stdin, stdout, pipeline.options...); err != nil {
+		logger.Error("agent failed", "error", err)
 		panic(err)
 	}
 
+	logger.Info("agent finished")
+
 	return nil
 }
 
@@ -76,7 +140,26 @@ func main() {
 				Email: "alexarnowork@gmail.com",
 			},
 		},
+		Flags: []cli.Flag{
+			&cli.PathFlag{
+				Name:    "trace",
+				Usage:   "record every protocol line exchanged with git-lfs to the given file",
+				EnvVars: []string{"YADLFS_TRACE"},
+			},
+		},
 		Action: action,
+		Commands: []*cli.Command{
+			cacheCommand(),
+			lsCommand(),
+			gcCommand(),
+			verifyRemoteCommand(),
+			migrateCommand(),
+			replayCommand(),
+			quotaCommand(),
+			doctorCommand(),
+			pushObjectsCommand(),
+			fetchObjectsCommand(),
+		},
 	}
 
 	if err := app.Run(os.Args); err != nil {
@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"errors"
 	"fmt"
 	"io"
 )
@@ -28,7 +29,11 @@ func (bcw *byteCountingWriter) Write(p []byte) (int, error) {
 		}
 	}
 
-	return n, fmt.Errorf("failed to write: %w", err)
+	if err != nil {
+		return n, fmt.Errorf("failed to write: %w", err)
+	}
+
+	return n, nil
 }
 
 type byteCountingReader struct {
@@ -55,5 +60,9 @@ func (bcr *byteCountingReader) Read(p []byte) (int, error) {
 		}
 	}
 
-	return n, fmt.Errorf("failed to read: %w", err)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return n, fmt.Errorf("failed to read: %w", err)
+	}
+
+	return n, err //nolint:wrapcheck // io.EOF must be returned as is
 }
@@ -0,0 +1,48 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestByteCountingWriter(t *testing.T) {
+	t.Parallel()
+
+	var total int64
+
+	writer := newByteCountingWriter(io.Discard, func(bytesSoFar, _ int64) { total = bytesSoFar })
+
+	n, err := writer.Write([]byte("data"))
+	require.NoError(t, err)
+	require.Equal(t, 4, n)
+	require.Equal(t, int64(4), total)
+}
+
+func TestByteCountingReader(t *testing.T) {
+	t.Parallel()
+
+	var total int64
+
+	reader := newByteCountingReader(strings.NewReader("data"), func(bytesSoFar, _ int64) { total = bytesSoFar })
+
+	// io.Copy and io.ReadAll only stop on a bare io.EOF
+	var out bytes.Buffer
+
+	_, err := io.Copy(&out, reader)
+	require.NoError(t, err)
+	require.Equal(t, "data", out.String())
+	require.Equal(t, int64(4), total)
+
+	_, err = reader.Read(make([]byte, 1))
+	require.Equal(t, io.EOF, err) //nolint:testifylint // io.EOF must not be wrapped
+
+	errBroken := errors.New("broken")
+	_, err = newByteCountingReader(iotest.ErrReader(errBroken), nil).Read(make([]byte, 1))
+	require.ErrorIs(t, err, errBroken)
+}
@@ -0,0 +1,424 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInvalidOID    = errors.New("invalid object id")
+	ErrCacheFolder   = errors.New("failed to resolve cache folder")
+	ErrCacheStore    = errors.New("failed to store object in cache")
+	ErrCacheEviction = errors.New("failed to evict cache objects")
+)
+
+const DefaultCacheMaxSize = 10 << 30 // 10 GiB
+
+var oidPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// IsOID reports whether s looks like a git-lfs object id (a hex sha256).
+func IsOID(s string) bool {
+	return oidPattern.MatchString(s)
+}
+
+// Cache is a content-addressed object store shared by all clones on the
+// machine. Objects are laid out like in git-lfs (`ab/cd/abcd...`) and the
+// least recently used ones are evicted once the cache outgrows maxSize.
+type Cache struct {
+	folder  string
+	maxSize int64
+	mu      sync.Mutex
+}
+
+func NewCache(folder string, maxSize int64) *Cache {
+	if maxSize <= 0 {
+		maxSize = DefaultCacheMaxSize
+	}
+
+	return &Cache{
+		folder:  folder,
+		maxSize: maxSize,
+	}
+}
+
+// DefaultCacheFolder returns `$XDG_CACHE_HOME/yadlfs` or the platform
+// equivalent.
+func DefaultCacheFolder() (string, error) {
+	folder, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrCacheFolder, err)
+	}
+
+	return filepath.Join(folder, "yadlfs"), nil
+}
+
+func (c *Cache) objectPath(oid string) string {
+	return filepath.Join(c.folder, "objects", oid[0:2], oid[2:4], oid)
+}
+
+// Fetch places the cached object at dst and returns its size. It reports
+// false when the object isn't cached or can't be placed.
+func (c *Cache) Fetch(oid, dst string) (int64, bool) {
+	if !oidPattern.MatchString(oid) {
+		return 0, false
+	}
+
+	src := c.objectPath(oid)
+
+	info, err := os.Stat(src)
+	if err != nil {
+		c.recordLookup(false)
+
+		return 0, false
+	}
+
+	if err := linkOrCopy(src, dst); err != nil {
+		c.recordLookup(false)
+
+		return 0, false
+	}
+
+	// mtime is used as the last access time for eviction
+	now := time.Now()
+	_ = os.Chtimes(src, now, now)
+
+	c.recordLookup(true)
+
+	return info.Size(), true
+}
+
+// Store adds the verified object at src to the cache and evicts old objects
+// if the cache grew too large.
+func (c *Cache) Store(oid, src string) error {
+	if !oidPattern.MatchString(oid) {
+		return fmt.Errorf("%w: %s", ErrInvalidOID, oid)
+	}
+
+	dst := c.objectPath(oid)
+
+	if _, err := os.Stat(dst); err == nil {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil { //nolint:mnd // rwxr-xr-x
+		return fmt.Errorf("%w: %w", ErrCacheStore, err)
+	}
+
+	// place the object under a temporary name first, so concurrent readers
+	// never see a partially written file
+	tmp := fmt.Sprintf("%s.%d.tmp", dst, os.Getpid())
+	if err := linkOrCopy(src, tmp); err != nil {
+		return fmt.Errorf("%w: %w", ErrCacheStore, err)
+	}
+
+	if err := os.Rename(tmp, dst); err != nil {
+		os.Remove(tmp)
+
+		return fmt.Errorf("%w: %w", ErrCacheStore, err)
+	}
+
+	_, err := c.Prune(c.maxSize, 0)
+
+	return err
+}
+
+type cacheEntry struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+func (c *Cache) entries() ([]cacheEntry, error) {
+	var entries []cacheEntry
+
+	root := filepath.Join(c.folder, "objects")
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() || !oidPattern.MatchString(d.Name()) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err //nolint:wrapcheck // wrapped by the caller
+		}
+
+		entries = append(entries, cacheEntry{path, info.Size(), info.ModTime()})
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk cache folder: %w", err)
+	}
+
+	return entries, nil
+}
+
+// CachePruneResult describes the objects removed by Prune.
+type CachePruneResult struct {
+	Objects int
+	Bytes   int64
+}
+
+// Prune removes objects not accessed for olderThan and then the least
+// recently used objects until the cache fits into maxSize. Zero values
+// disable the corresponding limit.
+func (c *Cache) Prune(maxSize int64, olderThan time.Duration) (CachePruneResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var result CachePruneResult
+
+	entries, err := c.entries()
+	if err != nil {
+		return result, fmt.Errorf("%w: %w", ErrCacheEviction, err)
+	}
+
+	var total int64
+	for _, entry := range entries {
+		total += entry.size
+	}
+
+	slices.SortFunc(entries, func(a, b cacheEntry) int {
+		return a.modTime.Compare(b.modTime)
+	})
+
+	deadline := time.Now().Add(-olderThan)
+
+	for _, entry := range entries {
+		expired := olderThan > 0 && entry.modTime.Before(deadline)
+		oversized := maxSize > 0 && total > maxSize
+
+		if !expired && !oversized {
+			break
+		}
+
+		if err := os.Remove(entry.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return result, fmt.Errorf("%w: %w", ErrCacheEviction, err)
+		}
+
+		total -= entry.size
+		result.Objects++
+		result.Bytes += entry.size
+	}
+
+	return result, nil
+}
+
+// CacheStats summarises the cache content and its effectiveness.
+type CacheStats struct {
+	Objects int   `json:"-"`
+	Bytes   int64 `json:"-"`
+	Hits    int64 `json:"hits"`
+	Misses  int64 `json:"misses"`
+}
+
+func (s CacheStats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+func (c *Cache) statsPath() string {
+	return filepath.Join(c.folder, "stats.json")
+}
+
+func (c *Cache) loadStats() CacheStats {
+	var stats CacheStats
+
+	data, err := os.ReadFile(c.statsPath())
+	if err != nil {
+		return stats
+	}
+
+	// a corrupted stats file only resets the counters
+	_ = json.Unmarshal(data, &stats)
+
+	return stats
+}
+
+// recordLookup persists the hit/miss counters. Counters are informational,
+// so failures and races between concurrent agents are ignored.
+func (c *Cache) recordLookup(hit bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.loadStats()
+	if hit {
+		stats.Hits++
+	} else {
+		stats.Misses++
+	}
+
+	data, err := json.Marshal(stats)
+	if err != nil {
+		return
+	}
+
+	if err := os.MkdirAll(c.folder, 0o755); err != nil { //nolint:mnd // rwxr-xr-x
+		return
+	}
+
+	tmp := fmt.Sprintf("%s.%d.tmp", c.statsPath(), os.Getpid())
+	if err := os.WriteFile(tmp, data, 0o644); err != nil { //nolint:gosec,mnd // rw-r--r--
+		return
+	}
+
+	_ = os.Rename(tmp, c.statsPath())
+}
+
+// Stats counts the cached objects and reads the lookup counters.
+func (c *Cache) Stats() (CacheStats, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entries, err := c.entries()
+	if err != nil {
+		return CacheStats{}, err
+	}
+
+	stats := c.loadStats()
+	stats.Objects = len(entries)
+
+	for _, entry := range entries {
+		stats.Bytes += entry.size
+	}
+
+	return stats, nil
+}
+
+// CacheVerifyResult describes the objects checked by Verify.
+type CacheVerifyResult struct {
+	Objects int
+	Corrupt []string
+}
+
+// Verify re-hashes every cached object and removes the ones whose content
+// doesn't match their OID.
+func (c *Cache) Verify() (CacheVerifyResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var result CacheVerifyResult
+
+	entries, err := c.entries()
+	if err != nil {
+		return result, err
+	}
+
+	for _, entry := range entries {
+		oid := filepath.Base(entry.path)
+
+		sum, err := hashFile(entry.path)
+		if err != nil {
+			return result, err
+		}
+
+		result.Objects++
+
+		if sum == oid {
+			continue
+		}
+
+		result.Corrupt = append(result.Corrupt, oid)
+
+		if err := os.Remove(entry.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return result, fmt.Errorf("%w: %w", ErrCacheEviction, err)
+		}
+	}
+
+	return result, nil
+}
+
+// VerifyObject checks the content of a file against its OID.
+func VerifyObject(path, oid string) error {
+	sum, err := hashFile(path)
+	if err != nil {
+		return err
+	}
+
+	if sum != oid {
+		return fmt.Errorf("%w: %s has sha256 %s", ErrDownloadChecksum, oid, sum)
+	}
+
+	return nil
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrOpenFile, err)
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", fmt.Errorf("%w: %w", ErrCopyData, err)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// linkOrCopy places src at dst trying the cheapest way first: a hardlink,
+// then a copy-on-write clone and finally a plain copy.
+func linkOrCopy(src, dst string) error {
+	if err := os.Remove(dst); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove %s: %w", dst, err)
+	}
+
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	}
+
+	if err := cloneFile(src, dst); err == nil {
+		return nil
+	}
+
+	return copyFile(src, dst)
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrOpenFile, err)
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644) //nolint:mnd // rw-r--r--
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrCreateFile, err)
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+
+		return fmt.Errorf("%w: %w", ErrCopyData, err)
+	}
+
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("%w: %w", ErrCopyData, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,119 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeCacheObject(t *testing.T, folder string, content string) (string, string) {
+	t.Helper()
+
+	sum := sha256.Sum256([]byte(content))
+	oid := hex.EncodeToString(sum[:])
+	path := filepath.Join(folder, oid)
+
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	return oid, path
+}
+
+func TestCacheStoreAndFetch(t *testing.T) {
+	t.Parallel()
+
+	cache := NewCache(t.TempDir(), 0)
+	workDir := t.TempDir()
+
+	oid, path := writeCacheObject(t, workDir, "cached object")
+	require.NoError(t, cache.Store(oid, path))
+
+	dst := filepath.Join(workDir, "restored")
+	size, ok := cache.Fetch(oid, dst)
+	require.True(t, ok)
+	require.Equal(t, int64(len("cached object")), size)
+
+	content, err := os.ReadFile(dst)
+	require.NoError(t, err)
+	require.Equal(t, "cached object", string(content))
+
+	_, ok = cache.Fetch("0000000000000000000000000000000000000000000000000000000000000000", dst)
+	require.False(t, ok)
+
+	_, ok = cache.Fetch("../../etc/passwd", dst)
+	require.False(t, ok)
+}
+
+func TestCacheEviction(t *testing.T) {
+	t.Parallel()
+
+	cache := NewCache(t.TempDir(), 10)
+	workDir := t.TempDir()
+
+	oldOID, oldPath := writeCacheObject(t, workDir, "old12")
+	require.NoError(t, cache.Store(oldOID, oldPath))
+
+	usedOID, usedPath := writeCacheObject(t, workDir, "used1")
+	require.NoError(t, cache.Store(usedOID, usedPath))
+
+	// make the first object the least recently used one
+	past := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(cache.objectPath(oldOID), past, past))
+
+	newOID, newPath := writeCacheObject(t, workDir, "new12")
+	require.NoError(t, cache.Store(newOID, newPath))
+
+	require.NoFileExists(t, cache.objectPath(oldOID))
+	require.FileExists(t, cache.objectPath(usedOID))
+	require.FileExists(t, cache.objectPath(newOID))
+}
+
+func TestCacheStatsAndVerify(t *testing.T) {
+	t.Parallel()
+
+	cache := NewCache(t.TempDir(), 0)
+	workDir := t.TempDir()
+
+	goodOID, goodPath := writeCacheObject(t, workDir, "good")
+	require.NoError(t, cache.Store(goodOID, goodPath))
+
+	badOID, badPath := writeCacheObject(t, workDir, "bad")
+	require.NoError(t, cache.Store(badOID, badPath))
+	require.NoError(t, os.WriteFile(cache.objectPath(badOID), []byte("corrupted"), 0o600))
+
+	_, ok := cache.Fetch(goodOID, filepath.Join(workDir, "restored"))
+	require.True(t, ok)
+
+	_, ok = cache.Fetch("1111111111111111111111111111111111111111111111111111111111111111", filepath.Join(workDir, "missing"))
+	require.False(t, ok)
+
+	stats, err := cache.Stats()
+	require.NoError(t, err)
+	require.Equal(t, 2, stats.Objects)
+	require.Equal(t, int64(len("good")+len("corrupted")), stats.Bytes)
+	require.InDelta(t, 0.5, stats.HitRate(), 0.001)
+
+	result, err := cache.Verify()
+	require.NoError(t, err)
+	require.Equal(t, 2, result.Objects)
+	require.Equal(t, []string{badOID}, result.Corrupt)
+	require.NoFileExists(t, cache.objectPath(badOID))
+	require.FileExists(t, cache.objectPath(goodOID))
+}
+
+func TestVerifyObject(t *testing.T) {
+	t.Parallel()
+
+	content := []byte("downloaded object")
+	sum := sha256.Sum256(content)
+	path := filepath.Join(t.TempDir(), "object")
+	require.NoError(t, os.WriteFile(path, content, 0o600))
+
+	require.NoError(t, VerifyObject(path, hex.EncodeToString(sum[:])))
+	require.ErrorIs(t, VerifyObject(path, "0a5070"), ErrDownloadChecksum)
+	require.ErrorIs(t, VerifyObject(filepath.Join(t.TempDir(), "missing"), "0a5070"), ErrOpenFile)
+}
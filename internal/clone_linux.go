@@ -0,0 +1,34 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// ficlone is the FICLONE ioctl request, which shares the extents of a file on
+// copy-on-write filesystems such as btrfs or xfs.
+const ficlone = 0x40049409
+
+func cloneFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrOpenFile, err)
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o644) //nolint:mnd // rw-r--r--
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrCreateFile, err)
+	}
+	defer out.Close()
+
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, out.Fd(), ficlone, in.Fd())
+	if errno != 0 {
+		os.Remove(dst)
+
+		return fmt.Errorf("failed to clone file: %w", errno)
+	}
+
+	return nil
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package internal
+
+import "errors"
+
+var errCloneUnsupported = errors.New("file cloning is not supported on this platform")
+
+func cloneFile(_, _ string) error {
+	return errCloneUnsupported
+}
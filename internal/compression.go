@@ -0,0 +1,201 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+)
+
+var (
+	ErrUnknownCompression = errors.New("unknown compression")
+	ErrCompressData       = errors.New("failed to compress data")
+)
+
+const (
+	compressionMagic     = "YDLZ"
+	compressionHeaderLen = len(compressionMagic) + 1
+	compressionSniffLen  = 512
+
+	compressionNone byte = 0
+	compressionGzip byte = 1
+)
+
+// incompressibleContentTypes lists sniffed content types which are already
+// compressed, so another compression pass only burns CPU.
+//
+//nolint:gochecknoglobals // read-only lookup table
+var incompressibleContentTypes = []string{
+	"application/gzip",
+	"application/x-gzip",
+	"application/zip",
+	"application/x-rar-compressed",
+	"application/pdf",
+	"application/wasm",
+	"application/ogg",
+	"audio/mpeg",
+	"audio/aac",
+	"audio/ogg",
+	"audio/wave",
+	"font/woff",
+	"font/woff2",
+	"image/gif",
+	"image/jpeg",
+	"image/png",
+	"image/webp",
+	"video/mp4",
+	"video/webm",
+	"video/avi",
+}
+
+// incompressibleMagics complements http.DetectContentType with formats it
+// doesn't know about.
+//
+//nolint:gochecknoglobals // read-only lookup table
+var incompressibleMagics = [][]byte{
+	{0x28, 0xb5, 0x2f, 0xfd},             // zstd
+	{0xfd, '7', 'z', 'X', 'Z', 0x00},     // xz
+	{'7', 'z', 0xbc, 0xaf, 0x27, 0x1c},   // 7z
+	{'B', 'Z', 'h'},                      // bzip2
+	{0x04, 0x22, 0x4d, 0x18},             // lz4
+	[]byte(compressionMagic),             // already processed by yadlfs
+	[]byte(encryptionMagic),              // encrypted data doesn't compress
+	{0x89, 'P', 'N', 'G', '\r', '\n'},    // png
+	{'P', 'K', 0x03, 0x04},               // zip, jar, docx, apk...
+	{'D', 'D', 'S', ' '},                 // textures are usually block compressed
+	{0xab, 'K', 'T', 'X', ' ', '1', '1'}, // ktx
+}
+
+func ParseCompression(name string) (byte, error) {
+	switch name {
+	case "", "none":
+		return compressionNone, nil
+	case "gzip":
+		return compressionGzip, nil
+	default:
+		return compressionNone, fmt.Errorf("%w: %s", ErrUnknownCompression, name)
+	}
+}
+
+func isIncompressible(head []byte) bool {
+	for _, magic := range incompressibleMagics {
+		if bytes.HasPrefix(head, magic) {
+			return true
+		}
+	}
+
+	return slices.Contains(incompressibleContentTypes, http.DetectContentType(head))
+}
+
+// CompressedRepository compresses objects before they are passed to the
+// wrapped repository. Every compressed object starts with a small marker, so
+// objects uploaded without compression are still downloaded as is.
+type CompressedRepository struct {
+	warehouse   Repository
+	compression byte
+}
+
+func NewCompressedRepository(warehouse Repository, compression byte) *CompressedRepository {
+	return &CompressedRepository{
+		warehouse:   warehouse,
+		compression: compression,
+	}
+}
+
+func (c *CompressedRepository) Upload(ctx context.Context, filePath string, r io.Reader, overwrite bool) error {
+	source := bufio.NewReaderSize(r, compressionSniffLen)
+
+	head, err := source.Peek(compressionSniffLen)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("%w: %w", ErrCompressData, err)
+	}
+
+	if c.compression == compressionNone || isIncompressible(head) {
+		// objects starting with the marker by accident must be marked as well,
+		// otherwise they would be mistaken for compressed ones on download
+		if bytes.HasPrefix(head, []byte(compressionMagic)) {
+			return c.warehouse.Upload(ctx, filePath, io.MultiReader(compressionHeader(compressionNone), source), overwrite) //nolint:wrapcheck,lll // caller wraps
+		}
+
+		return c.warehouse.Upload(ctx, filePath, source, overwrite) //nolint:wrapcheck // caller wraps
+	}
+
+	pipeReader, pipeWriter := io.Pipe()
+
+	go func() {
+		pipeWriter.CloseWithError(compressGzip(pipeWriter, source))
+	}()
+
+	defer pipeReader.Close()
+
+	return c.warehouse.Upload(ctx, filePath, io.MultiReader(compressionHeader(c.compression), pipeReader), overwrite) //nolint:wrapcheck,lll // caller wraps
+}
+
+func (c *CompressedRepository) Download(ctx context.Context, path string) (io.ReadCloser, error) {
+	body, err := c.warehouse.Download(ctx, path)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // caller wraps
+	}
+
+	source := bufio.NewReader(body)
+
+	head, err := source.Peek(compressionHeaderLen)
+	if err != nil && !errors.Is(err, io.EOF) {
+		body.Close()
+
+		return nil, fmt.Errorf("%w: %w", ErrDownloadFailed, err)
+	}
+
+	if !bytes.HasPrefix(head, []byte(compressionMagic)) || len(head) < compressionHeaderLen {
+		return &readCloser{source, body}, nil
+	}
+
+	algorithm := head[len(compressionMagic)]
+
+	if _, err := source.Discard(compressionHeaderLen); err != nil {
+		body.Close()
+
+		return nil, fmt.Errorf("%w: %w", ErrDownloadFailed, err)
+	}
+
+	switch algorithm {
+	case compressionNone:
+		return &readCloser{source, body}, nil
+	case compressionGzip:
+		gzipReader, err := gzip.NewReader(source)
+		if err != nil {
+			body.Close()
+
+			return nil, fmt.Errorf("%w: %w", ErrDownloadFailed, err)
+		}
+
+		return &readCloser{gzipReader, body}, nil
+	default:
+		body.Close()
+
+		return nil, fmt.Errorf("%w: %d", ErrUnknownCompression, algorithm)
+	}
+}
+
+func compressionHeader(algorithm byte) io.Reader {
+	return bytes.NewReader(append([]byte(compressionMagic), algorithm))
+}
+
+func compressGzip(w io.Writer, r io.Reader) error {
+	gzipWriter := gzip.NewWriter(w)
+
+	if _, err := io.Copy(gzipWriter, r); err != nil {
+		return fmt.Errorf("%w: %w", ErrCompressData, err)
+	}
+
+	if err := gzipWriter.Close(); err != nil {
+		return fmt.Errorf("%w: %w", ErrCompressData, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package internal
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/alxarno/yadlfs/internal/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompressedRepository(t *testing.T) {
+	t.Parallel()
+
+	csv := bytes.Repeat([]byte("id,name,value\n1,yadlfs,42\n"), 4096)
+	png := append([]byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'}, csv...)
+	marked := append([]byte(compressionMagic), csv...)
+
+	tests := []struct {
+		name       string
+		data       []byte
+		compressed bool
+	}{
+		{"Text", csv, true},
+		{"Empty", []byte{}, true},
+		{"AlreadyCompressed", png, false},
+		{"StartsWithMarker", marked, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			memory := mocks.NewMemoryRepository()
+			repo := NewCompressedRepository(memory, compressionGzip)
+
+			require.NoError(t, repo.Upload(t.Context(), "oid", bytes.NewReader(tt.data), true))
+
+			stored := memory.Objects["oid"]
+			if tt.compressed {
+				require.True(t, bytes.HasPrefix(stored, []byte(compressionMagic)))
+				require.Less(t, len(stored), max(len(tt.data), 64))
+			} else {
+				require.False(t, bytes.HasPrefix(stored, append([]byte(compressionMagic), compressionGzip)))
+			}
+
+			body, err := repo.Download(t.Context(), "oid")
+			require.NoError(t, err)
+
+			restored, err := io.ReadAll(body)
+			require.NoError(t, err)
+			require.Equal(t, tt.data, restored)
+		})
+	}
+}
+
+func TestCompressedRepositoryLegacyObject(t *testing.T) {
+	t.Parallel()
+
+	memory := mocks.NewMemoryRepository()
+	memory.Objects["oid"] = []byte("uploaded before compression was enabled")
+
+	body, err := NewCompressedRepository(memory, compressionGzip).Download(t.Context(), "oid")
+	require.NoError(t, err)
+
+	restored, err := io.ReadAll(body)
+	require.NoError(t, err)
+	require.Equal(t, memory.Objects["oid"], restored)
+}
+
+func TestWrapRepositoryWithoutCompression(t *testing.T) {
+	t.Parallel()
+
+	// a plain object which happens to start with the compression marker
+	plain := append([]byte(compressionMagic), compressionGzip, 'x')
+
+	for _, compression := range []string{"", "none"} {
+		memory := mocks.NewMemoryRepository()
+
+		warehouse, err := WrapRepository(&Config{Compression: compression}, memory)
+		require.NoError(t, err)
+		require.NoError(t, warehouse.Upload(t.Context(), "oid", bytes.NewReader(plain), true))
+		require.Equal(t, plain, memory.Objects["oid"], "the remote must hold the object as is")
+
+		body, err := warehouse.Download(t.Context(), "oid")
+		require.NoError(t, err)
+
+		restored, err := io.ReadAll(body)
+		require.NoError(t, err)
+		require.Equal(t, plain, restored)
+	}
+}
@@ -2,26 +2,74 @@ package internal
 
 import (
 	"fmt"
+	"log/slog"
+	"net/url"
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/caarlos0/env/v11"
 	"github.com/goccy/go-yaml"
 )
 
+const defaultTransferIdleTimeout = time.Minute
+
+// Storage backends of a remote. The agent talks to Yandex Disk only, S3
+// remotes are supported by migrate.
+const (
+	BackendYandex = "yandex"
+	BackendS3     = "s3"
+)
+
+// secretConfigFields are masked whenever the config is shown.
+//
+//nolint:gochecknoglobals // read-only lookup table
+var secretConfigFields = []string{"YandexDiskOAuthToken", "EncryptionPassphrase", "S3SecretAccessKey"}
+
 type Config struct {
-	YandexDiskOAuthToken    string `env:"YANDEX_DISK_OAUTH_TOKEN,required"    yaml:"yandexDiskOauthToken"`
-	YandexDiskProjectFolder string `env:"YANDEX_DISK_PROJECT_FOLDER,required" yaml:"yandexDiskProjectFolder"`
+	YandexDiskOAuthToken    string        `env:"YANDEX_DISK_OAUTH_TOKEN,required"    yaml:"yandexDiskOauthToken"`
+	YandexDiskProjectFolder string        `env:"YANDEX_DISK_PROJECT_FOLDER,required" yaml:"yandexDiskProjectFolder"`
+	EncryptionKeyFile       string        `env:"YADLFS_ENCRYPTION_KEYFILE"           envDefault:""                       yaml:"encryptionKeyFile"`
+	EncryptionPassphrase    string        `env:"YADLFS_ENCRYPTION_PASSPHRASE"        envDefault:""                       yaml:"encryptionPassphrase"`
+	Compression             string        `env:"YADLFS_COMPRESSION"                  envDefault:""                       yaml:"compression"`
+	CacheFolder             string        `env:"YADLFS_CACHE_DIR"                    envDefault:""                       yaml:"cacheDir"`
+	CacheMaxSize            int64         `env:"YADLFS_CACHE_MAX_SIZE"               envDefault:"0"                      yaml:"cacheMaxSize"`
+	CacheDisabled           bool          `env:"YADLFS_CACHE_DISABLED"               envDefault:"false"                  yaml:"cacheDisabled"`
+	LogFile                 string        `env:"YADLFS_LOG"                          envDefault:""                       yaml:"logFile"`
+	LogLevel                string        `env:"YADLFS_LOG_LEVEL"                    envDefault:"info"                   yaml:"logLevel"`
+	StatsFile               string        `env:"YADLFS_STATS_FILE"                   envDefault:""                       yaml:"statsFile"`
+	MetricsFile             string        `env:"YADLFS_METRICS_FILE"                 envDefault:""                       yaml:"metricsFile"`
+	TransferIdleTimeout     time.Duration `env:"YADLFS_TRANSFER_IDLE_TIMEOUT"        envDefault:"60s"                    yaml:"transferIdleTimeout"`
+	TransferTimeout         time.Duration `env:"YADLFS_TRANSFER_TIMEOUT"             envDefault:"0s"                     yaml:"transferTimeout"`
+	HTTPProxy               string        `env:"YADLFS_HTTP_PROXY"                   envDefault:""                       yaml:"httpProxy"`
+	HTTPCABundle            string        `env:"YADLFS_HTTP_CA_BUNDLE"               envDefault:""                       yaml:"httpCaBundle"`
+	HTTPMaxIdleConns        int           `env:"YADLFS_HTTP_MAX_IDLE_CONNS"          envDefault:"0"                      yaml:"httpMaxIdleConns"`
+	HTTPMaxConnsPerHost     int           `env:"YADLFS_HTTP_MAX_CONNS_PER_HOST"      envDefault:"0"                      yaml:"httpMaxConnsPerHost"`
+	HTTPTLSHandshakeTimeout time.Duration `env:"YADLFS_HTTP_TLS_HANDSHAKE_TIMEOUT"   envDefault:"0s"                     yaml:"httpTlsHandshakeTimeout"`
+	HTTPResponseTimeout     time.Duration `env:"YADLFS_HTTP_RESPONSE_HEADER_TIMEOUT" envDefault:"0s"                     yaml:"httpResponseHeaderTimeout"`
+	UploadChunkSize         int64         `env:"YADLFS_UPLOAD_CHUNK_SIZE"            envDefault:"0"                      yaml:"uploadChunkSize"`
+	DownloadParts           int           `env:"YADLFS_DOWNLOAD_PARTS"               envDefault:"1"                      yaml:"downloadParts"`
+	DownloadPartsMinSize    int64         `env:"YADLFS_DOWNLOAD_PARTS_MIN_SIZE"      envDefault:"67108864"               yaml:"downloadPartsMinSize"`
+	TmpFolder               string        `env:"YADLFS_TMP_DIR"                      envDefault:""                       yaml:"tmpDir"`
+	TmpMaxAge               time.Duration `env:"YADLFS_TMP_MAX_AGE"                  envDefault:"24h"                    yaml:"tmpMaxAge"`
+	Backend                 string        `env:"YADLFS_BACKEND"                      envDefault:"yandex"                 yaml:"backend"`
+	S3Endpoint              string        `env:"YADLFS_S3_ENDPOINT"                  envDefault:""                       yaml:"s3Endpoint"`
+	S3Region                string        `env:"YADLFS_S3_REGION"                    envDefault:"us-east-1"              yaml:"s3Region"`
+	S3Bucket                string        `env:"YADLFS_S3_BUCKET"                    envDefault:""                       yaml:"s3Bucket"`
+	S3Prefix                string        `env:"YADLFS_S3_PREFIX"                    envDefault:""                       yaml:"s3Prefix"`
+	S3AccessKeyID           string        `env:"YADLFS_S3_ACCESS_KEY_ID"             envDefault:""                       yaml:"s3AccessKeyId"`
+	S3SecretAccessKey       string        `env:"YADLFS_S3_SECRET_ACCESS_KEY"         envDefault:""                       yaml:"s3SecretAccessKey"`
 }
 
 func LoadConfig() (*Config, error) {
-	pwd, err := os.Getwd()
+	configFilePath, err := configFile()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get current working directory: %w", err)
+		return nil, err
 	}
 
-	configFilePath := filepath.Join(pwd, ".yadlfs.yaml")
-
 	if _, err := os.Stat(configFilePath); err == nil {
 		return loadConfigFromYAML(configFilePath)
 	} else if os.IsNotExist(err) {
@@ -31,13 +79,114 @@ func LoadConfig() (*Config, error) {
 	return nil, fmt.Errorf("failed to check for .yadlfs.yaml file: %w", err)
 }
 
+func configFile() (string, error) {
+	pwd, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get current working directory: %w", err)
+	}
+
+	return filepath.Join(pwd, ".yadlfs.yaml"), nil
+}
+
+// ConfigSetting is a config value together with the place it came from.
+type ConfigSetting struct {
+	Name   string
+	Value  string
+	Source string
+}
+
+// DescribeConfig reports where each setting of the config LoadConfig would
+// load comes from. Secrets are masked.
+func DescribeConfig(config *Config) ([]ConfigSetting, error) {
+	configFilePath, err := configFile()
+	if err != nil {
+		return nil, err
+	}
+
+	var fileKeys map[string]any
+
+	if data, err := os.ReadFile(configFilePath); err == nil {
+		if err := yaml.Unmarshal(data, &fileKeys); err != nil {
+			return nil, fmt.Errorf("failed to parse YAML file: %w", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read YAML file: %w", err)
+	}
+
+	value := reflect.ValueOf(config).Elem()
+	settings := make([]ConfigSetting, 0, value.NumField())
+
+	for i := range value.NumField() {
+		field := value.Type().Field(i)
+		yamlKey := field.Tag.Get("yaml")
+		envName, _, _ := strings.Cut(field.Tag.Get("env"), ",")
+
+		setting := ConfigSetting{Name: yamlKey, Value: fmt.Sprint(value.Field(i).Interface()), Source: "default"}
+
+		if fileKeys != nil {
+			if _, ok := fileKeys[yamlKey]; ok {
+				setting.Source = filepath.Base(configFilePath)
+			}
+		} else if _, ok := os.LookupEnv(envName); ok {
+			setting.Source = "$" + envName
+		}
+
+		setting.Value = maskSetting(field.Name, setting.Value)
+		settings = append(settings, setting)
+	}
+
+	return settings, nil
+}
+
+// LogValue logs the settings of the config with the secrets masked.
+func (c *Config) LogValue() slog.Value {
+	value := reflect.ValueOf(c).Elem()
+	attrs := make([]slog.Attr, 0, value.NumField())
+
+	for i := range value.NumField() {
+		field := value.Type().Field(i)
+		setting := maskSetting(field.Name, fmt.Sprint(value.Field(i).Interface()))
+		attrs = append(attrs, slog.String(field.Tag.Get("yaml"), setting))
+	}
+
+	return slog.GroupValue(attrs...)
+}
+
+// maskSetting hides secrets and the credentials of URLs in a setting value.
+func maskSetting(field, value string) string {
+	if slices.Contains(secretConfigFields, field) && value != "" {
+		return redacted
+	}
+
+	// proxy URLs may carry credentials
+	if u, err := url.Parse(value); err == nil && u.User != nil {
+		return u.Redacted()
+	}
+
+	return value
+}
+
+// LoadConfigFile loads the config from the given YAML file, e.g. to describe
+// another remote.
+func LoadConfigFile(filePath string) (*Config, error) {
+	return loadConfigFromYAML(filePath)
+}
+
 func loadConfigFromYAML(filePath string) (*Config, error) {
 	data, err := os.ReadFile(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read YAML file: %w", err)
 	}
 
-	var config Config
+	// YAML has no default tags, so defaults are set before decoding
+	config := Config{
+		TransferIdleTimeout:  defaultTransferIdleTimeout,
+		DownloadParts:        1,
+		DownloadPartsMinSize: DefaultRangedDownloadMinSize,
+		TmpMaxAge:            DefaultTmpMaxAge,
+		Backend:              BackendYandex,
+		S3Region:             "us-east-1",
+	}
 	if err := yaml.Unmarshal(data, &config); err != nil {
 		return nil, fmt.Errorf("failed to parse YAML file: %w", err)
 	}
@@ -55,3 +204,30 @@ func loadConfigFromEnv() (*Config, error) {
 
 	return &config, nil
 }
+
+// StoresPlainObjects reports whether objects are stored on Yandex Disk as is,
+// so their remote size and sha256 match the git-lfs pointer.
+func (c *Config) StoresPlainObjects() bool {
+	return c.EncryptionKeyFile == "" && c.EncryptionPassphrase == "" &&
+		(c.Compression == "" || c.Compression == "none")
+}
+
+// LoadCache returns the local object cache described by the config, or nil
+// when caching is disabled.
+func LoadCache(config *Config) (*Cache, error) {
+	if config.CacheDisabled {
+		return nil, nil //nolint:nilnil // cache is optional
+	}
+
+	folder := config.CacheFolder
+	if folder == "" {
+		defaultFolder, err := DefaultCacheFolder()
+		if err != nil {
+			return nil, err
+		}
+
+		folder = defaultFolder
+	}
+
+	return NewCache(folder, config.CacheMaxSize), nil
+}
@@ -2,12 +2,18 @@ package internal
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"sync/atomic"
+	"time"
 
+	"github.com/alxarno/yadlfs/pkg"
 	"golang.org/x/sync/semaphore"
 )
 
@@ -19,78 +25,288 @@ var (
 	ErrDownloadFailed       = errors.New("download failed")
 	ErrCopyData             = errors.New("failed to copy data")
 	ErrSemaphoreAcquire     = errors.New("failed to acquire semaphore")
+	ErrDuplicateInit        = errors.New("session is already initialized")
+	ErrTransferBeforeInit   = errors.New("transfer received before init")
+	ErrEventMismatch        = errors.New("transfer event doesn't match the session operation")
 )
 
-type repository interface {
+// Error codes reported to git-lfs. They follow the HTTP status semantics,
+// git-lfs only shows them to the user.
+const (
+	ErrorCodeGeneric             int64 = 0
+	ErrorCodeProtocol            int64 = 400
+	ErrorCodeStalled             int64 = 408
+	ErrorCodeDeadline            int64 = 504
+	ErrorCodeInsufficientStorage int64 = 507
+)
+
+// transferEvents are the transfer events expected in a session of the
+// operation.
+//
+//nolint:gochecknoglobals // read-only lookup table
+var transferEvents = map[OperationName]EventName{
+	OperationNameUpload:   EventNameUpload,
+	OperationNameDownload: EventNameDownload,
+}
+
+type Repository interface {
 	Upload(ctx context.Context, filePath string, r io.Reader, overwrite bool) error
 	Download(ctx context.Context, path string) (io.ReadCloser, error)
 }
 
 type Controller struct {
-	messages  chan DialMessage
-	operation OperationName
-	semaphore *semaphore.Weighted
-	warehouse repository
-	folder    string
+	messages    chan DialMessage
+	initialized bool
+	operation   OperationName
+	semaphore   *semaphore.Weighted
+	slots       int64
+	warehouse   Repository
+	folder      string
+	cache       *Cache
+	logger      *slog.Logger
+	stats       *SessionStats
+	statsFile   string
+	metrics     *Metrics
+	metricsFile string
+	idleTimeout time.Duration
+	deadline    time.Duration
+	ranged      *rangedDownloads
+	quota       *quota
+}
+
+type ControllerOption func(*Controller)
+
+// WithCache makes the controller look up downloads in the local object cache
+// first and populate it with verified downloads.
+func WithCache(cache *Cache) ControllerOption {
+	return func(s *Controller) {
+		s.cache = cache
+	}
 }
 
-func NewController(warehouse repository, folder string, messages chan DialMessage) *Controller {
-	return &Controller{
+// WithLogger sets the logger transfers are reported to.
+func WithLogger(logger *slog.Logger) ControllerOption {
+	return func(s *Controller) {
+		s.logger = logger
+	}
+}
+
+// WithStatsFile makes the controller write the session summary as JSON to
+// the given file on terminate.
+func WithStatsFile(path string) ControllerOption {
+	return func(s *Controller) {
+		s.statsFile = path
+	}
+}
+
+// WithMetricsFile makes the controller export the session metrics in the
+// OpenMetrics text format to the given file on terminate.
+func WithMetricsFile(path string, metrics *Metrics) ControllerOption {
+	return func(s *Controller) {
+		s.metricsFile = path
+		s.metrics = metrics
+	}
+}
+
+// WithTransferTimeouts limits transfers: a transfer which doesn't progress for
+// idle or runs longer than deadline is aborted, so it can't hold a
+// concurrency slot forever. Zero values disable the limits.
+func WithTransferTimeouts(idle, deadline time.Duration) ControllerOption {
+	return func(s *Controller) {
+		s.idleTimeout = idle
+		s.deadline = deadline
+	}
+}
+
+func NewController(warehouse Repository, folder string, messages chan DialMessage, opts ...ControllerOption) *Controller {
+	controller := &Controller{
 		messages:  messages,
 		semaphore: semaphore.NewWeighted(1),
+		slots:     1,
 		warehouse: warehouse,
 		folder:    folder,
+		logger:    slog.New(slog.DiscardHandler),
+		stats:     NewSessionStats(),
+	}
+
+	for _, opt := range opts {
+		opt(controller)
 	}
+
+	return controller
+}
+
+type transferResult struct {
+	bytes  int64
+	cached bool
 }
 
-func (s *Controller) upload(ctx context.Context, event Transfer) error {
+func (s *Controller) upload(ctx context.Context, event Transfer, watchdog *stallWatchdog) (transferResult, error) {
 	f, err := os.Open(event.Path)
 	if err != nil {
-		return fmt.Errorf("%w: %w", ErrOpenFile, err)
+		return transferResult{}, fmt.Errorf("%w: %w", ErrOpenFile, err)
 	}
+	defer f.Close()
 
-	countingReader := newUploadFileProgress(f, event, s.messages)
+	if err := s.quota.reserve(event.Size); err != nil {
+		return transferResult{}, err
+	}
+
+	// the body is read by the transport goroutine, which may outlive a failed upload
+	var uploaded atomic.Int64
+
+	countingReader := newByteCountingReader(newUploadFileProgress(f, event, s.messages), func(bytesSoFar, _ int64) {
+		uploaded.Store(bytesSoFar)
+		watchdog.touch()
+	})
 
 	if err = s.warehouse.Upload(ctx, event.OID, countingReader, true); err != nil {
-		return fmt.Errorf("%w: %w", ErrUploadFailed, err)
+		s.quota.release(event.Size)
+
+		return transferResult{bytes: uploaded.Load()}, fmt.Errorf("%w: %w", ErrUploadFailed, err)
 	}
 
-	return nil
+	return transferResult{bytes: uploaded.Load()}, nil
 }
 
-func (s *Controller) download(ctx context.Context, event Transfer) error {
-	path := filepath.Join(s.folder, event.OID)
-	fileMode := 665
+func (s *Controller) download(ctx context.Context, event Transfer, watchdog *stallWatchdog) (transferResult, error) {
+	path := s.downloadPath(event.OID)
+
+	if err := os.MkdirAll(s.folder, 0o755); err != nil { //nolint:mnd // rwxr-xr-x
+		return transferResult{}, fmt.Errorf("%w: %w", ErrCreateFile, err)
+	}
+
+	if s.cache != nil {
+		if size, ok := s.cache.Fetch(event.OID, path); ok {
+			s.logger.DebugContext(ctx, "cache hit", "oid", event.OID, "size", size)
 
-	outputFile, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND, os.FileMode(fileMode))
+			s.messages <- ProgressMessage{OID: event.OID, BytesSoFar: 0, BytesSinceLast: 0}
+			s.messages <- ProgressMessage{OID: event.OID, BytesSoFar: size, BytesSinceLast: size}
+
+			return transferResult{bytes: size, cached: true}, nil
+		}
+	}
+
+	outputFile, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644) //nolint:mnd // rw-r--r--
 	if err != nil {
-		return fmt.Errorf("%w: %w", ErrCreateFile, err)
+		return transferResult{}, fmt.Errorf("%w: %w", ErrCreateFile, err)
+	}
+	defer outputFile.Close()
+
+	if s.rangedDownload(event) {
+		written, err := s.downloadRanges(ctx, event, outputFile, watchdog)
+
+		switch {
+		case err == nil:
+			s.storeInCache(ctx, event.OID, path)
+
+			return transferResult{bytes: written}, nil
+		case errors.Is(err, pkg.ErrRangesNotSupported):
+			s.logger.DebugContext(ctx, "storage doesn't accept ranges, streaming the object", "oid", event.OID)
+		case errors.Is(err, ErrDownloadChecksum):
+			// objects stored encrypted or compressed only decode through the repository
+			s.logger.DebugContext(ctx, "ranges don't match the oid, streaming the object", "oid", event.OID)
+		default:
+			return transferResult{bytes: written}, err
+		}
+
+		if err := rewindFile(outputFile); err != nil {
+			return transferResult{}, err
+		}
 	}
 
 	downloadReader, err := s.warehouse.Download(ctx, event.OID)
 	if err != nil {
-		return fmt.Errorf("%w: %w", ErrDownloadFailed, err)
+		return transferResult{}, fmt.Errorf("%w: %w", ErrDownloadFailed, err)
 	}
+	defer downloadReader.Close()
 
-	countingWriter := newDownloadFileProgress(outputFile, event, s.messages)
+	hash := sha256.New()
+	watchdogWriter := newByteCountingWriter(io.MultiWriter(outputFile, hash), func(_, _ int64) {
+		watchdog.touch()
+	})
+	countingWriter := newDownloadFileProgress(watchdogWriter, event, s.messages)
 
-	_, err = io.Copy(countingWriter, downloadReader)
+	written, err := io.Copy(countingWriter, downloadReader)
 	if err != nil {
-		return fmt.Errorf("%w: %w", ErrCopyData, err)
+		return transferResult{bytes: written}, fmt.Errorf("%w: %w", ErrCopyData, err)
+	}
+
+	// only objects matching their OID get into the cache
+	if hex.EncodeToString(hash.Sum(nil)) == event.OID {
+		s.storeInCache(ctx, event.OID, path)
+	}
+
+	return transferResult{bytes: written}, nil
+}
+
+// rewindFile drops the partial content of a download before it's retried.
+func rewindFile(file *os.File) error {
+	if err := file.Truncate(0); err != nil {
+		return fmt.Errorf("%w: %w", ErrCreateFile, err)
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("%w: %w", ErrCreateFile, err)
 	}
 
 	return nil
 }
 
-func (s *Controller) init(m Init) error {
+// storeInCache copies a verified download into the cache. The cache is best
+// effort, so failing to populate it doesn't fail the transfer.
+func (s *Controller) storeInCache(ctx context.Context, oid, path string) {
+	if s.cache == nil {
+		return
+	}
+
+	if err := s.cache.Store(oid, path); err != nil {
+		s.logger.WarnContext(ctx, "failed to populate cache", "oid", oid, "error", err)
+	}
+}
+
+func (s *Controller) init(ctx context.Context, m Init) error {
+	// the first init defines the session, a second one can't change it
+	if s.initialized {
+		s.logger.WarnContext(ctx, "init rejected", "operation", m.Operation, "error", ErrDuplicateInit)
+		s.messages <- InitErrorMessage{Error: CompleteErrorMessageContent{ErrorCodeProtocol, ErrDuplicateInit.Error()}}
+
+		return nil
+	}
+
+	s.initialized = true
+
+	s.logger.Info(
+		"session started",
+		"operation", m.Operation,
+		"remote", m.Remote,
+		"concurrentTransfers", m.ConcurrentTransfers,
+	)
+
 	s.operation = m.Operation
-	s.semaphore = semaphore.NewWeighted(max(m.ConcurrentTransfers, 1))
+	s.slots = max(m.ConcurrentTransfers, 1)
+
+	// the check is best effort, uploads still fail with 507 without it
+	if s.operation == OperationNameUpload {
+		if err := s.quota.refresh(ctx); err != nil {
+			s.logger.WarnContext(ctx, "failed to check quota", "error", err)
+		}
+	}
+
+	s.semaphore = semaphore.NewWeighted(s.slots)
 	s.messages <- ConfirmMessage{}
 
 	return nil
 }
 
 func (s *Controller) transfer(ctx context.Context, event Transfer) error {
+	if err := s.validateTransfer(event); err != nil {
+		s.logger.WarnContext(ctx, "transfer rejected", "oid", event.OID, "event", event.Event, "error", err)
+		s.sendErrorMessage(event.OID, err)
+
+		return nil
+	}
+
 	if err := s.semaphore.Acquire(ctx, 1); err != nil {
 		return fmt.Errorf("%w: %w", ErrSemaphoreAcquire, err)
 	}
@@ -100,36 +316,157 @@ func (s *Controller) transfer(ctx context.Context, event Transfer) error {
 	return nil
 }
 
+// validateTransfer rejects transfers which don't belong to the session, a
+// download event in an upload session would otherwise be uploaded.
+func (s *Controller) validateTransfer(event Transfer) error {
+	if !s.initialized {
+		return ErrTransferBeforeInit
+	}
+
+	if expected := transferEvents[s.operation]; event.Event != expected {
+		return fmt.Errorf("%w: %s event in %s session", ErrEventMismatch, event.Event, s.operation)
+	}
+
+	return nil
+}
+
+// drain waits for all running transfers to finish.
+func (s *Controller) drain(ctx context.Context) error {
+	if err := s.semaphore.Acquire(ctx, s.slots); err != nil {
+		return fmt.Errorf("%w: %w", ErrSemaphoreAcquire, err)
+	}
+
+	s.semaphore.Release(s.slots)
+
+	return nil
+}
+
+// terminate waits for running transfers and reports the session summary.
+func (s *Controller) terminate(ctx context.Context) error {
+	if err := s.drain(ctx); err != nil {
+		return err
+	}
+
+	summary := s.stats.Summary(s.operation)
+
+	s.logger.InfoContext(
+		ctx,
+		"session summary",
+		"operation", summary.Operation,
+		"duration", summary.Duration,
+		"objects", summary.Objects,
+		"completed", summary.Completed,
+		"skipped", summary.Skipped,
+		"failed", summary.Failed,
+		"bytes", summary.Bytes,
+		"throughput", summary.Throughput,
+	)
+
+	if s.statsFile != "" {
+		if err := writeSessionSummary(s.statsFile, summary); err != nil {
+			s.logger.WarnContext(ctx, "failed to write stats file", "error", err)
+		}
+	}
+
+	if s.metrics != nil {
+		if err := s.metrics.WriteFile(s.metricsFile, summary); err != nil {
+			s.logger.WarnContext(ctx, "failed to write metrics file", "error", err)
+		}
+	}
+
+	return nil
+}
+
 func (s *Controller) handleTransfer(ctx context.Context, event Transfer) {
 	defer s.semaphore.Release(1)
 
-	var err error
+	var (
+		result transferResult
+		err    error
+	)
+
+	start := time.Now()
+	logger := s.logger.With("oid", event.OID, "operation", s.operation, "size", event.Size)
+	logger.DebugContext(ctx, "transfer started")
+
+	ctx, watchdog, cancel := newTransferContext(ctx, s.idleTimeout, s.deadline)
+	defer cancel()
 
 	switch s.operation {
 	case OperationNameDownload:
-		err = s.download(ctx, event)
+		result, err = s.download(ctx, event, watchdog)
 	case OperationNameUpload:
-		err = s.upload(ctx, event)
+		result, err = s.upload(ctx, event, watchdog)
 	default:
 		err = fmt.Errorf("%w: %s", ErrUnsupportedOperation, s.operation)
 	}
 
 	if err != nil {
+		err = transferError(ctx, err)
+	}
+
+	stats := TransferStats{
+		OID:      event.OID,
+		Size:     event.Size,
+		Bytes:    result.bytes,
+		Duration: time.Since(start),
+		Cached:   result.cached,
+	}
+
+	if err != nil {
+		stats.Code = errorCode(err)
+		stats.Error = err.Error()
+		s.stats.record(stats)
+
+		logger.ErrorContext(ctx, "transfer failed", "duration", stats.Duration, "error", err)
 		s.sendErrorMessage(event.OID, err)
 
 		return
 	}
 
+	s.stats.record(stats)
+
+	logger.InfoContext(ctx, "transfer complete", "duration", stats.Duration, "bytes", stats.Bytes, "cached", stats.Cached)
+
+	// git-lfs moves downloads from the reported path into its store
+	if s.operation == OperationNameDownload {
+		path := s.downloadPath(event.OID)
+		s.messages <- CompleteMessage{OID: event.OID, Path: &path}
+
+		return
+	}
+
 	s.sendCompletionMessage(event.OID)
 }
 
+func (s *Controller) downloadPath(oid string) string {
+	return filepath.Join(s.folder, oid)
+}
+
 func (s *Controller) sendCompletionMessage(oid string) {
 	s.messages <- CompleteMessage{OID: oid}
 }
 
+// errorCode classifies transfer errors for git-lfs, which treats any code as
+// a failure, and for the metrics.
+func errorCode(err error) int64 {
+	switch {
+	case errors.Is(err, ErrTransferBeforeInit), errors.Is(err, ErrEventMismatch):
+		return ErrorCodeProtocol
+	case errors.Is(err, ErrTransferStalled):
+		return ErrorCodeStalled
+	case errors.Is(err, ErrTransferDeadline):
+		return ErrorCodeDeadline
+	case errors.Is(err, ErrQuotaExceeded), errors.Is(err, pkg.ErrInsufficientStorage):
+		return ErrorCodeInsufficientStorage
+	default:
+		return ErrorCodeGeneric
+	}
+}
+
 func (s *Controller) sendErrorMessage(oid string, err error) {
 	s.messages <- CompleteErrorMessage{
 		OID:   oid,
-		Error: CompleteErrorMessageContent{0, err.Error()},
+		Error: CompleteErrorMessageContent{errorCode(err), err.Error()},
 	}
 }
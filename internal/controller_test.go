@@ -224,7 +224,7 @@ func TestDownload(t *testing.T) {
 		Path: filepath.Join(tempDir, "file.bin"),
 		ExpectedProgress: []string{
 			`{"event":"progress","oid":"0a5070","bytesSoFar":0,"bytesSinceLast":0}`,
-			`{"event":"complete","oid":"0a5070"}`,
+			fmt.Sprintf(`{"event":"complete","oid":"0a5070","path":%q}`, filepath.Join(tempDir, "0a5070")),
 		},
 	}
 
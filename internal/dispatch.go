@@ -2,6 +2,7 @@ package internal
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -14,6 +15,7 @@ var (
 	ErrParseTransferMessage = errors.New("failed to parse transfer message")
 	ErrUnknownMessageType   = errors.New("unknown message type")
 	ErrDispatchFailed       = errors.New("dispatch failed")
+	ErrReadMessage          = errors.New("failed to read message")
 )
 
 type messageDispatch func(context.Context, []byte) error
@@ -27,13 +29,13 @@ func NewDispatcher(stdin io.Reader, controller *Controller) *Dispatcher {
 	return &Dispatcher{stdin, controller}
 }
 
-func (d *Dispatcher) initMessage(_ context.Context, raw []byte) error {
+func (d *Dispatcher) initMessage(ctx context.Context, raw []byte) error {
 	var msg Init
 	if err := json.Unmarshal(raw, &msg); err != nil {
 		return fmt.Errorf("%w: %w", ErrParseInitMessage, err)
 	}
 
-	return d.controller.init(msg)
+	return d.controller.init(ctx, msg)
 }
 
 func (d *Dispatcher) transferMessage(ctx context.Context, raw []byte) error {
@@ -45,7 +47,14 @@ func (d *Dispatcher) transferMessage(ctx context.Context, raw []byte) error {
 	return d.controller.transfer(ctx, msg)
 }
 
-func (d *Dispatcher) terminateMessage(_ context.Context, _ []byte) error {
+// terminateMessage lets running transfers report their results before the
+// dispatcher stops, git-lfs sends it only after receiving all of them, but a
+// replayed session doesn't wait.
+func (d *Dispatcher) terminateMessage(ctx context.Context, _ []byte) error {
+	if err := d.controller.terminate(ctx); err != nil {
+		return err
+	}
+
 	return io.EOF
 }
 
@@ -73,17 +82,32 @@ func (d *Dispatcher) dispatch(ctx context.Context, msg []byte) error {
 	return parser(ctx, msg)
 }
 
+// ListenAndServe dispatches the messages read from stdin line by line. Lines
+// aren't limited in length, paths may be arbitrarily long. It returns nil
+// when stdin ends without terminate.
 func (d *Dispatcher) ListenAndServe(ctx context.Context) error {
-	scanner := bufio.NewScanner(d.stdin)
-
-	for scanner.Scan() {
-		err := d.dispatch(ctx, scanner.Bytes())
-		if err != nil && errors.Is(err, io.EOF) {
-			return io.EOF
-		} else if err != nil {
-			return fmt.Errorf("%w: %w", ErrDispatchFailed, err)
+	reader := bufio.NewReader(d.stdin)
+
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if readErr != nil && !errors.Is(readErr, io.EOF) {
+			return fmt.Errorf("%w: %w", ErrReadMessage, readErr)
 		}
-	}
 
-	return nil
+		// the last line may come without a newline
+		if len(line) > 0 {
+			line = bytes.TrimSuffix(bytes.TrimSuffix(line, []byte("\n")), []byte("\r"))
+
+			err := d.dispatch(ctx, line)
+			if err != nil && errors.Is(err, io.EOF) {
+				return io.EOF
+			} else if err != nil {
+				return fmt.Errorf("%w: %w", ErrDispatchFailed, err)
+			}
+		}
+
+		if readErr != nil {
+			return nil
+		}
+	}
 }
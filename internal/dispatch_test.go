@@ -0,0 +1,194 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/alxarno/yadlfs/internal/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestDispatcher returns a dispatcher reading input and the replies of its
+// controller.
+func newTestDispatcher(t *testing.T, input io.Reader) (*Dispatcher, <-chan DialMessage) {
+	t.Helper()
+
+	messages := make(chan DialMessage)
+	replies := make(chan DialMessage, 16)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	t.Cleanup(cancel)
+
+	go func() {
+		for {
+			select {
+			case msg := <-messages:
+				select {
+				case replies <- msg:
+				default:
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	controller := NewController(mocks.NewMemoryRepository(), t.TempDir(), messages)
+
+	return NewDispatcher(input, controller), replies
+}
+
+func TestDispatcherLongLines(t *testing.T) {
+	t.Parallel()
+
+	// the default bufio.Scanner token is limited to 64 KiB
+	remote := strings.Repeat("r", 256<<10)
+	input := `{"event":"init","operation":"upload","remote":"` + remote + `","concurrenttransfers":1}` + "\r\n" +
+		`{"event":"terminate"}`
+
+	dispatcher, replies := newTestDispatcher(t, strings.NewReader(input))
+
+	require.ErrorIs(t, dispatcher.ListenAndServe(t.Context()), io.EOF)
+	require.Equal(t, ConfirmMessage{}, <-replies)
+}
+
+func TestDispatcherEndOfInput(t *testing.T) {
+	t.Parallel()
+
+	dispatcher, replies := newTestDispatcher(t, strings.NewReader(`{"event":"init","operation":"upload"}`))
+
+	require.NoError(t, dispatcher.ListenAndServe(t.Context()))
+	require.Equal(t, ConfirmMessage{}, <-replies)
+}
+
+func TestDispatcherReadError(t *testing.T) {
+	t.Parallel()
+
+	errBroken := errors.New("broken pipe")
+	dispatcher, _ := newTestDispatcher(t, iotest.ErrReader(errBroken))
+
+	err := dispatcher.ListenAndServe(t.Context())
+	require.ErrorIs(t, err, ErrReadMessage)
+	require.ErrorIs(t, err, errBroken)
+}
+
+func TestDispatcherProtocolErrors(t *testing.T) {
+	t.Parallel()
+
+	protocolError := func(oid, message string) DialMessage {
+		return CompleteErrorMessage{OID: oid, Error: CompleteErrorMessageContent{ErrorCodeProtocol, message}}
+	}
+
+	tests := []struct {
+		name    string
+		input   []string
+		replies []DialMessage
+	}{
+		{
+			"TransferBeforeInit",
+			[]string{`{"event":"upload","oid":"early","size":1,"path":"file.bin"}`},
+			[]DialMessage{protocolError("early", "transfer received before init")},
+		},
+		{
+			"DownloadInUploadSession",
+			[]string{
+				`{"event":"init","operation":"upload","concurrenttransfers":1}`,
+				`{"event":"download","oid":"wrong","size":1}`,
+			},
+			[]DialMessage{
+				ConfirmMessage{},
+				protocolError("wrong", "transfer event doesn't match the session operation: download event in upload session"),
+			},
+		},
+		{
+			"SecondInit",
+			[]string{
+				`{"event":"init","operation":"upload","concurrenttransfers":1}`,
+				`{"event":"init","operation":"download","concurrenttransfers":1}`,
+				// the session is still an upload one
+				`{"event":"download","oid":"wrong","size":1}`,
+			},
+			[]DialMessage{
+				ConfirmMessage{},
+				InitErrorMessage{Error: CompleteErrorMessageContent{ErrorCodeProtocol, "session is already initialized"}},
+				protocolError("wrong", "transfer event doesn't match the session operation: download event in upload session"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			input := strings.Join(append(tt.input, `{"event":"terminate"}`), "\n")
+			dispatcher, replies := newTestDispatcher(t, strings.NewReader(input))
+
+			require.ErrorIs(t, dispatcher.ListenAndServe(t.Context()), io.EOF)
+
+			for _, expected := range tt.replies {
+				require.Equal(t, expected, <-replies)
+			}
+
+			require.Empty(t, replies)
+		})
+	}
+}
+
+func TestInitErrorMessageMarshal(t *testing.T) {
+	t.Parallel()
+
+	data, err := InitErrorMessage{Error: CompleteErrorMessageContent{ErrorCodeProtocol, "bad"}}.Marshal()
+	require.NoError(t, err)
+	require.JSONEq(t, `{"error":{"code":400,"message":"bad"}}`, string(data))
+}
+
+func FuzzDispatch(f *testing.F) {
+	for _, seed := range []string{
+		`{"event":"init","operation":"upload","remote":"origin","concurrent":true,"concurrenttransfers":3}`,
+		`{"event":"init","operation":"download","concurrenttransfers":-1}`,
+		`{"event":"upload","oid":"0a5070","size":1024,"path":"file.bin"}`,
+		`{"event":"download","oid":"0a5070","size":1024}`,
+		`{"event":"terminate"}`,
+		`{"event":"progress"}`,
+		`{"event":1}`,
+		`{"event":"init","concurrenttransfers":"3"}`,
+		`{"event":"upload","size":"big"}`,
+		`{"event":`,
+		`[]`,
+		`null`,
+		``,
+	} {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, msg []byte) {
+		dispatcher, _ := newTestDispatcher(t, nil)
+
+		err := dispatcher.dispatch(t.Context(), msg)
+
+		// transfers run in the background, they must not outlive the test
+		require.NoError(t, dispatcher.controller.drain(t.Context()))
+
+		if !json.Valid(msg) {
+			require.ErrorIs(t, err, ErrUnknownMessageType)
+
+			return
+		}
+
+		if err != nil {
+			require.True(
+				t,
+				errors.Is(err, io.EOF) ||
+					errors.Is(err, ErrUnknownMessageType) ||
+					errors.Is(err, ErrParseInitMessage) ||
+					errors.Is(err, ErrParseTransferMessage),
+				"unexpected error: %v", err,
+			)
+		}
+	})
+}
@@ -0,0 +1,410 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+var (
+	ErrEncryptionKey       = errors.New("invalid encryption key")
+	ErrEncryptionHeader    = errors.New("invalid encryption header")
+	ErrEncryptionVersion   = errors.New("unsupported encryption version")
+	ErrDecryptChunk        = errors.New("failed to decrypt chunk")
+	ErrEncryptionTruncated = errors.New("encrypted object is truncated")
+)
+
+const (
+	encryptionMagic     = "YDLE"
+	encryptionVersion   = 1
+	encryptionKeySize   = 32
+	encryptionSaltSize  = 16
+	encryptionNonceSize = 8
+	encryptionChunkSize = 64 * 1024
+	encryptionTagSize   = 16
+	encryptionHeaderLen = len(encryptionMagic) + 2 + encryptionSaltSize + encryptionNonceSize
+
+	kdfNone   byte = 0
+	kdfScrypt byte = 1
+
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+
+	// derivedKeysLimit bounds the keys kept for objects uploaded by other
+	// sessions, each of them has its own salt.
+	derivedKeysLimit = 16
+)
+
+// EncryptionKey holds either a raw AES-256 key or a passphrase which is
+// stretched with scrypt using the salt stored in every object header.
+type EncryptionKey struct {
+	raw        []byte
+	passphrase []byte
+}
+
+// LoadEncryptionKey resolves the encryption key from the config.
+// It returns nil when encryption is not configured.
+func LoadEncryptionKey(config *Config) (*EncryptionKey, error) {
+	if config.EncryptionKeyFile != "" && config.EncryptionPassphrase != "" {
+		return nil, fmt.Errorf("%w: both key file and passphrase are set", ErrEncryptionKey)
+	}
+
+	if config.EncryptionPassphrase != "" {
+		return &EncryptionKey{passphrase: []byte(config.EncryptionPassphrase)}, nil
+	}
+
+	if config.EncryptionKeyFile == "" {
+		return nil, nil //nolint:nilnil // encryption is optional
+	}
+
+	data, err := os.ReadFile(config.EncryptionKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrEncryptionKey, err)
+	}
+
+	return parseEncryptionKey(data)
+}
+
+func parseEncryptionKey(data []byte) (*EncryptionKey, error) {
+	if len(data) == encryptionKeySize {
+		return &EncryptionKey{raw: data}, nil
+	}
+
+	key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: key file must hold 32 raw bytes or 64 hex characters", ErrEncryptionKey)
+	}
+
+	if len(key) != encryptionKeySize {
+		return nil, fmt.Errorf("%w: expected %d bytes, got %d", ErrEncryptionKey, encryptionKeySize, len(key))
+	}
+
+	return &EncryptionKey{raw: key}, nil
+}
+
+// EncryptedRepository encrypts objects with AES-256-GCM before they are passed
+// to the wrapped repository and decrypts them on the way back.
+type EncryptedRepository struct {
+	warehouse Repository
+	key       *EncryptionKey
+
+	mu          sync.Mutex
+	sessionSalt []byte
+	derived     map[string][]byte
+	// derivedOrder lists the derived keys from the oldest one for eviction
+	derivedOrder []string
+}
+
+func NewEncryptedRepository(warehouse Repository, key *EncryptionKey) *EncryptedRepository {
+	return &EncryptedRepository{
+		warehouse: warehouse,
+		key:       key,
+		derived:   map[string][]byte{},
+	}
+}
+
+func (e *EncryptedRepository) Upload(ctx context.Context, filePath string, r io.Reader, overwrite bool) error {
+	header := encryptionHeader{version: encryptionVersion, kdf: kdfNone}
+
+	if _, err := rand.Read(header.nonce[:]); err != nil {
+		return fmt.Errorf("%w: %w", ErrEncryptionHeader, err)
+	}
+
+	if e.key.passphrase != nil {
+		header.kdf = kdfScrypt
+		if err := e.readSessionSalt(header.salt[:]); err != nil {
+			return err
+		}
+	}
+
+	aead, err := e.aead(header)
+	if err != nil {
+		return err
+	}
+
+	reader := io.MultiReader(bytes.NewReader(header.marshal()), newEncryptingReader(r, aead, header.nonce))
+
+	return e.warehouse.Upload(ctx, filePath, reader, overwrite) //nolint:wrapcheck // caller wraps
+}
+
+func (e *EncryptedRepository) Download(ctx context.Context, path string) (io.ReadCloser, error) {
+	body, err := e.warehouse.Download(ctx, path)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // caller wraps
+	}
+
+	raw := make([]byte, encryptionHeaderLen)
+	if _, err := io.ReadFull(body, raw); err != nil {
+		body.Close()
+
+		return nil, fmt.Errorf("%w: %w", ErrEncryptionHeader, err)
+	}
+
+	header, err := unmarshalEncryptionHeader(raw)
+	if err != nil {
+		body.Close()
+
+		return nil, err
+	}
+
+	aead, err := e.aead(header)
+	if err != nil {
+		body.Close()
+
+		return nil, err
+	}
+
+	return &readCloser{newDecryptingReader(body, aead, header.nonce), body}, nil
+}
+
+func (e *EncryptedRepository) aead(header encryptionHeader) (cipher.AEAD, error) {
+	key, err := e.resolveKey(header)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrEncryptionKey, err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrEncryptionKey, err)
+	}
+
+	return aead, nil
+}
+
+func (e *EncryptedRepository) resolveKey(header encryptionHeader) ([]byte, error) {
+	switch header.kdf {
+	case kdfNone:
+		if e.key.raw == nil {
+			return nil, fmt.Errorf("%w: object was encrypted with a key file", ErrEncryptionKey)
+		}
+
+		return e.key.raw, nil
+	case kdfScrypt:
+		if e.key.passphrase == nil {
+			return nil, fmt.Errorf("%w: object was encrypted with a passphrase", ErrEncryptionKey)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown key derivation %d", ErrEncryptionHeader, header.kdf)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if key, ok := e.derived[string(header.salt[:])]; ok {
+		return key, nil
+	}
+
+	key, err := scrypt.Key(e.key.passphrase, header.salt[:], scryptN, scryptR, scryptP, encryptionKeySize)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrEncryptionKey, err)
+	}
+
+	if len(e.derivedOrder) == derivedKeysLimit {
+		delete(e.derived, e.derivedOrder[0])
+		e.derivedOrder = e.derivedOrder[1:]
+	}
+
+	e.derived[string(header.salt[:])] = key
+	e.derivedOrder = append(e.derivedOrder, string(header.salt[:]))
+
+	return key, nil
+}
+
+// readSessionSalt copies the salt of the uploads into salt. It's generated
+// once, so scrypt runs once per session rather than per object, while the
+// random nonces keep the objects apart.
+func (e *EncryptedRepository) readSessionSalt(salt []byte) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.sessionSalt == nil {
+		e.sessionSalt = make([]byte, encryptionSaltSize)
+		if _, err := rand.Read(e.sessionSalt); err != nil {
+			e.sessionSalt = nil
+
+			return fmt.Errorf("%w: %w", ErrEncryptionHeader, err)
+		}
+	}
+
+	copy(salt, e.sessionSalt)
+
+	return nil
+}
+
+type encryptionHeader struct {
+	version byte
+	kdf     byte
+	salt    [encryptionSaltSize]byte
+	nonce   [encryptionNonceSize]byte
+}
+
+func (h encryptionHeader) marshal() []byte {
+	raw := make([]byte, 0, encryptionHeaderLen)
+	raw = append(raw, encryptionMagic...)
+	raw = append(raw, h.version, h.kdf)
+	raw = append(raw, h.salt[:]...)
+	raw = append(raw, h.nonce[:]...)
+
+	return raw
+}
+
+func unmarshalEncryptionHeader(raw []byte) (encryptionHeader, error) {
+	var header encryptionHeader
+
+	if len(raw) != encryptionHeaderLen || string(raw[:len(encryptionMagic)]) != encryptionMagic {
+		return header, ErrEncryptionHeader
+	}
+
+	raw = raw[len(encryptionMagic):]
+	header.version, header.kdf = raw[0], raw[1]
+
+	if header.version != encryptionVersion {
+		return header, fmt.Errorf("%w: %d", ErrEncryptionVersion, header.version)
+	}
+
+	copy(header.salt[:], raw[2:])
+	copy(header.nonce[:], raw[2+encryptionSaltSize:])
+
+	return header, nil
+}
+
+// chunkNonce builds a per chunk nonce from the random object prefix and the
+// chunk counter, so chunks can't be reordered without failing authentication.
+func chunkNonce(prefix [encryptionNonceSize]byte, counter uint32) []byte {
+	nonce := make([]byte, 0, len(prefix)+4) //nolint:mnd // uint32 counter
+	nonce = append(nonce, prefix[:]...)
+
+	return binary.BigEndian.AppendUint32(nonce, counter)
+}
+
+// chunkAdditionalData marks the last chunk, so truncating the object on a
+// chunk boundary is detected on decryption.
+func chunkAdditionalData(final bool) []byte {
+	if final {
+		return []byte{1}
+	}
+
+	return []byte{0}
+}
+
+// encryptingReader seals the plaintext in fixed size chunks. Every chunk but
+// the last one holds exactly encryptionChunkSize bytes, the last one is always
+// shorter (possibly empty).
+type encryptingReader struct {
+	source  io.Reader
+	aead    cipher.AEAD
+	prefix  [encryptionNonceSize]byte
+	counter uint32
+	plain   []byte
+	pending []byte
+	done    bool
+}
+
+func newEncryptingReader(source io.Reader, aead cipher.AEAD, prefix [encryptionNonceSize]byte) *encryptingReader {
+	return &encryptingReader{
+		source: source,
+		aead:   aead,
+		prefix: prefix,
+		plain:  make([]byte, encryptionChunkSize),
+	}
+}
+
+func (er *encryptingReader) Read(p []byte) (int, error) {
+	for len(er.pending) == 0 {
+		if er.done {
+			return 0, io.EOF
+		}
+
+		n, err := io.ReadFull(er.source, er.plain)
+		final := errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+
+		if err != nil && !final {
+			return 0, err //nolint:wrapcheck // source error is returned as is
+		}
+
+		nonce := chunkNonce(er.prefix, er.counter)
+		er.pending = er.aead.Seal(er.pending[:0], nonce, er.plain[:n], chunkAdditionalData(final))
+		er.counter++
+		er.done = final
+	}
+
+	n := copy(p, er.pending)
+	er.pending = er.pending[n:]
+
+	return n, nil
+}
+
+type decryptingReader struct {
+	source  io.Reader
+	aead    cipher.AEAD
+	prefix  [encryptionNonceSize]byte
+	counter uint32
+	sealed  []byte
+	pending []byte
+	done    bool
+}
+
+func newDecryptingReader(source io.Reader, aead cipher.AEAD, prefix [encryptionNonceSize]byte) *decryptingReader {
+	return &decryptingReader{
+		source: source,
+		aead:   aead,
+		prefix: prefix,
+		sealed: make([]byte, encryptionChunkSize+encryptionTagSize),
+	}
+}
+
+func (dr *decryptingReader) Read(p []byte) (int, error) {
+	for len(dr.pending) == 0 {
+		if dr.done {
+			return 0, io.EOF
+		}
+
+		n, err := io.ReadFull(dr.source, dr.sealed)
+		if errors.Is(err, io.EOF) {
+			return 0, ErrEncryptionTruncated
+		}
+
+		final := errors.Is(err, io.ErrUnexpectedEOF)
+		if err != nil && !final {
+			return 0, err //nolint:wrapcheck // source error is returned as is
+		}
+
+		nonce := chunkNonce(dr.prefix, dr.counter)
+
+		dr.pending, err = dr.aead.Open(dr.pending[:0], nonce, dr.sealed[:n], chunkAdditionalData(final))
+		if err != nil {
+			return 0, fmt.Errorf("%w %d: %w", ErrDecryptChunk, dr.counter, err)
+		}
+
+		dr.counter++
+		dr.done = final
+	}
+
+	n := copy(p, dr.pending)
+	dr.pending = dr.pending[n:]
+
+	return n, nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,144 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"testing"
+
+	"github.com/alxarno/yadlfs/internal/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptedRepositoryRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	sizes := []int{0, 1, encryptionChunkSize - 1, encryptionChunkSize, 3*encryptionChunkSize + 17}
+	keys := map[string]*EncryptionKey{
+		"KeyFile":    {raw: bytes.Repeat([]byte{7}, encryptionKeySize)},
+		"Passphrase": {passphrase: []byte("correct horse battery staple")},
+	}
+
+	for name, key := range keys {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			memory := mocks.NewMemoryRepository()
+			repo := NewEncryptedRepository(memory, key)
+
+			for _, size := range sizes {
+				plain := make([]byte, size)
+				_, err := rand.Read(plain)
+				require.NoError(t, err)
+
+				require.NoError(t, repo.Upload(t.Context(), "oid", bytes.NewReader(plain), true))
+				if size > encryptionTagSize {
+					require.NotContains(t, string(memory.Objects["oid"]), string(plain), "plaintext leaked to the backend")
+				}
+
+				body, err := repo.Download(t.Context(), "oid")
+				require.NoError(t, err)
+
+				decrypted, err := io.ReadAll(body)
+				require.NoError(t, err)
+				require.Equal(t, plain, decrypted, "size %d", size)
+			}
+		})
+	}
+}
+
+func TestEncryptedRepositoryDerivedKeys(t *testing.T) {
+	t.Parallel()
+
+	key := &EncryptionKey{passphrase: []byte("correct horse battery staple")}
+	memory := mocks.NewMemoryRepository()
+
+	// a session derives a single key for all of its uploads
+	repo := NewEncryptedRepository(memory, key)
+	for _, oid := range []string{"a", "b", "c"} {
+		require.NoError(t, repo.Upload(t.Context(), oid, bytes.NewReader([]byte(oid)), true))
+	}
+
+	require.Len(t, repo.derived, 1)
+
+	// keys of other sessions are bounded, the oldest one is evicted
+	for i := range derivedKeysLimit - 1 {
+		salt := string(rune('A' + i))
+		repo.derived[salt] = nil
+		repo.derivedOrder = append(repo.derivedOrder, salt)
+	}
+
+	oldest := repo.derivedOrder[0]
+	other := NewEncryptedRepository(memory, key)
+	require.NoError(t, other.Upload(t.Context(), "d", bytes.NewReader([]byte("d")), true))
+
+	body, err := repo.Download(t.Context(), "d")
+	require.NoError(t, err)
+
+	decrypted, err := io.ReadAll(body)
+	require.NoError(t, err)
+	require.Equal(t, []byte("d"), decrypted)
+	require.Len(t, repo.derived, derivedKeysLimit)
+	require.NotContains(t, repo.derived, oldest)
+}
+
+func TestEncryptedRepositoryTampering(t *testing.T) {
+	t.Parallel()
+
+	memory := mocks.NewMemoryRepository()
+	repo := NewEncryptedRepository(memory, &EncryptionKey{raw: bytes.Repeat([]byte{1}, encryptionKeySize)})
+
+	plain := bytes.Repeat([]byte("yadlfs"), encryptionChunkSize)
+	require.NoError(t, repo.Upload(t.Context(), "oid", bytes.NewReader(plain), true))
+
+	sealed := memory.Objects["oid"]
+
+	t.Run("Truncated", func(t *testing.T) {
+		t.Parallel()
+
+		truncated := mocks.NewMemoryRepository()
+		truncated.Objects["oid"] = sealed[:encryptionHeaderLen+encryptionChunkSize+encryptionTagSize]
+
+		body, err := NewEncryptedRepository(truncated, repo.key).Download(t.Context(), "oid")
+		require.NoError(t, err)
+
+		_, err = io.ReadAll(body)
+		require.ErrorIs(t, err, ErrEncryptionTruncated)
+	})
+
+	t.Run("Modified", func(t *testing.T) {
+		t.Parallel()
+
+		modified := mocks.NewMemoryRepository()
+		modified.Objects["oid"] = bytes.Clone(sealed)
+		modified.Objects["oid"][encryptionHeaderLen+10] ^= 0xff
+
+		body, err := NewEncryptedRepository(modified, repo.key).Download(t.Context(), "oid")
+		require.NoError(t, err)
+
+		_, err = io.ReadAll(body)
+		require.ErrorIs(t, err, ErrDecryptChunk)
+	})
+
+	t.Run("WrongKey", func(t *testing.T) {
+		t.Parallel()
+
+		wrongKey := &EncryptionKey{raw: bytes.Repeat([]byte{2}, encryptionKeySize)}
+
+		body, err := NewEncryptedRepository(memory, wrongKey).Download(t.Context(), "oid")
+		require.NoError(t, err)
+
+		_, err = io.ReadAll(body)
+		require.ErrorIs(t, err, ErrDecryptChunk)
+	})
+}
+
+func TestParseEncryptionKey(t *testing.T) {
+	t.Parallel()
+
+	_, err := parseEncryptionKey([]byte("0011223344556677889900112233445566778899001122334455667788990011\n"))
+	require.NoError(t, err)
+
+	_, err = parseEncryptionKey([]byte("too short"))
+	require.ErrorIs(t, err, ErrEncryptionKey)
+}
@@ -78,6 +78,16 @@ func (m CompleteErrorMessage) Marshal() ([]byte, error) {
 	return json.Marshal(m)
 }
 
+// InitErrorMessage rejects an init message, git-lfs expects the error
+// without an event.
+type InitErrorMessage struct {
+	Error CompleteErrorMessageContent `json:"error"`
+}
+
+func (m InitErrorMessage) Marshal() ([]byte, error) {
+	return json.Marshal(m)
+}
+
 type ConfirmMessage struct {
 }
 
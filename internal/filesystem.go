@@ -0,0 +1,62 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// FilesystemRepository stores objects in a local folder. It's used to
+// replay recorded sessions and to test the agent without Yandex Disk.
+type FilesystemRepository struct {
+	root string
+}
+
+func NewFilesystemRepository(root string) *FilesystemRepository {
+	return &FilesystemRepository{root: root}
+}
+
+func (f *FilesystemRepository) Upload(_ context.Context, filePath string, r io.Reader, overwrite bool) error {
+	path := filepath.Join(f.root, filepath.Base(filePath))
+
+	if _, err := os.Stat(path); err == nil && !overwrite {
+		return nil
+	}
+
+	if err := os.MkdirAll(f.root, 0o755); err != nil { //nolint:mnd // rwxr-xr-x
+		return fmt.Errorf("%w: %w", ErrCreateFile, err)
+	}
+
+	tmp, err := os.CreateTemp(f.root, filepath.Base(filePath)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrCreateFile, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+
+		return fmt.Errorf("%w: %w", ErrCopyData, err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("%w: %w", ErrCopyData, err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("%w: %w", ErrCreateFile, err)
+	}
+
+	return nil
+}
+
+func (f *FilesystemRepository) Download(_ context.Context, path string) (io.ReadCloser, error) {
+	file, err := os.Open(filepath.Join(f.root, filepath.Base(path)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrOpenFile, err)
+	}
+
+	return file, nil
+}
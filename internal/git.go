@@ -0,0 +1,198 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrGitCommand     = errors.New("git command failed")
+	ErrGitBatchOutput = errors.New("unexpected git cat-file output")
+)
+
+const (
+	// pointerMaxSize is the biggest blob git-lfs considers to be a pointer.
+	pointerMaxSize = 1024
+
+	pointerVersion   = "version https://git-lfs.github.com/spec/v1"
+	pointerOIDPrefix = "oid sha256:"
+	pointerSizeKey   = "size "
+)
+
+// Pointer is a git-lfs pointer file stored in the git history.
+type Pointer struct {
+	OID  string
+	Size int64
+}
+
+// ListPointers scans the blobs reachable from refs for git-lfs pointers.
+// It relies on plain git only, so it works without git-lfs installed.
+func ListPointers(ctx context.Context, refs []string) ([]Pointer, error) {
+	if len(refs) == 0 {
+		refs = []string{"--all"}
+	}
+
+	objects, err := runGit(ctx, nil, append([]string{"rev-list", "--objects"}, refs...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates bytes.Buffer
+
+	scanner := bufio.NewScanner(bytes.NewReader(objects))
+	for scanner.Scan() {
+		hash, _, _ := strings.Cut(scanner.Text(), " ")
+		candidates.WriteString(hash + "\n")
+	}
+
+	checks, err := runGit(ctx, &candidates, "cat-file", "--batch-check=%(objectname) %(objecttype) %(objectsize)")
+	if err != nil {
+		return nil, err
+	}
+
+	var blobs bytes.Buffer
+
+	scanner = bufio.NewScanner(bytes.NewReader(checks))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 || fields[1] != "blob" { //nolint:mnd // name, type, size
+			continue
+		}
+
+		if size, err := strconv.ParseInt(fields[2], 10, 64); err == nil && size < pointerMaxSize {
+			blobs.WriteString(fields[0] + "\n")
+		}
+	}
+
+	contents, err := runGit(ctx, &blobs, "cat-file", "--batch")
+	if err != nil {
+		return nil, err
+	}
+
+	return parseBatchPointers(contents)
+}
+
+// parseBatchPointers parses `git cat-file --batch` output and returns unique
+// pointers in the order of their first appearance.
+func parseBatchPointers(contents []byte) ([]Pointer, error) {
+	var pointers []Pointer
+
+	seen := map[string]bool{}
+	reader := bufio.NewReader(bytes.NewReader(contents))
+
+	for {
+		header, err := reader.ReadString('\n')
+		if errors.Is(err, io.EOF) {
+			return pointers, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrGitBatchOutput, err)
+		}
+
+		fields := strings.Fields(header)
+		if len(fields) != 3 { //nolint:mnd // name, type, size
+			return nil, fmt.Errorf("%w: %q", ErrGitBatchOutput, header)
+		}
+
+		size, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrGitBatchOutput, err)
+		}
+
+		// content is followed by a newline
+		body := make([]byte, size+1)
+		if _, err := io.ReadFull(reader, body); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrGitBatchOutput, err)
+		}
+
+		if pointer, ok := parsePointer(body[:size]); ok && !seen[pointer.OID] {
+			seen[pointer.OID] = true
+			pointers = append(pointers, pointer)
+		}
+	}
+}
+
+func parsePointer(data []byte) (Pointer, bool) {
+	var pointer Pointer
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) < 3 || lines[0] != pointerVersion { //nolint:mnd // version, oid, size
+		return pointer, false
+	}
+
+	for _, line := range lines[1:] {
+		if oid, ok := strings.CutPrefix(line, pointerOIDPrefix); ok {
+			pointer.OID = oid
+		} else if size, ok := strings.CutPrefix(line, pointerSizeKey); ok {
+			value, err := strconv.ParseInt(size, 10, 64)
+			if err != nil {
+				return pointer, false
+			}
+
+			pointer.Size = value
+		}
+	}
+
+	return pointer, oidPattern.MatchString(pointer.OID)
+}
+
+// GitOutput runs a git command in the current directory and returns its
+// trimmed output.
+func GitOutput(ctx context.Context, args ...string) (string, error) {
+	output, err := runGit(ctx, nil, args...)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
+// IsShallowRepository reports whether the current repository is a shallow
+// clone, whose history doesn't reference every object.
+func IsShallowRepository(ctx context.Context) (bool, error) {
+	output, err := GitOutput(ctx, "rev-parse", "--is-shallow-repository")
+	if err != nil {
+		return false, err
+	}
+
+	return output == "true", nil
+}
+
+// GitCommonDir returns the absolute path of the git dir shared by the
+// worktrees of the current repository.
+func GitCommonDir(ctx context.Context) (string, error) {
+	output, err := GitOutput(ctx, "rev-parse", "--git-common-dir")
+	if err != nil {
+		return "", err
+	}
+
+	// git prints the dir relative to the working directory
+	dir, err := filepath.Abs(output)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrGitCommand, err)
+	}
+
+	return dir, nil
+}
+
+func runGit(ctx context.Context, stdin io.Reader, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Stdin = stdin
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%w: git %s: %w: %s", ErrGitCommand, args[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,117 @@
+//nolint:paralleltest
+package internal
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testPointerA = "1111111111111111111111111111111111111111111111111111111111111111"
+	testPointerB = "2222222222222222222222222222222222222222222222222222222222222222"
+)
+
+func pointerContent(oid string, size int64) string {
+	return fmt.Sprintf("version https://git-lfs.github.com/spec/v1\noid sha256:%s\nsize %d\n", oid, size)
+}
+
+func gitCommit(t *testing.T, files map[string]string) {
+	t.Helper()
+
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(name, []byte(content), 0o600))
+	}
+
+	for _, args := range [][]string{{"add", "-A"}, {"commit", "-q", "-m", "commit"}} {
+		out, err := exec.Command("git", args...).CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+}
+
+func TestListPointers(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	t.Chdir(t.TempDir())
+	t.Setenv("GIT_AUTHOR_NAME", "yadlfs")
+	t.Setenv("GIT_AUTHOR_EMAIL", "yadlfs@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "yadlfs")
+	t.Setenv("GIT_COMMITTER_EMAIL", "yadlfs@example.com")
+
+	out, err := exec.Command("git", "init", "-q", "-b", "main").CombinedOutput()
+	require.NoError(t, err, string(out))
+
+	gitCommit(t, map[string]string{
+		"a.bin":     pointerContent(testPointerA, 1),
+		"README.md": "version https://git-lfs.github.com/spec/v1 is not a pointer",
+	})
+
+	// the pointer is replaced, but it's still reachable from the history
+	gitCommit(t, map[string]string{
+		"a.bin": pointerContent(testPointerB, 2),
+		"b.bin": pointerContent(testPointerB, 2),
+	})
+
+	pointers, err := ListPointers(t.Context(), nil)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []Pointer{{testPointerA, 1}, {testPointerB, 2}}, pointers)
+
+	pointers, err = ListPointers(t.Context(), []string{"main~1"})
+	require.NoError(t, err)
+	require.Equal(t, []Pointer{{testPointerA, 1}}, pointers)
+
+	_, err = ListPointers(t.Context(), []string{"unknown-ref"})
+	require.ErrorIs(t, err, ErrGitCommand)
+}
+
+func TestGitOutput(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	output, err := GitOutput(t.Context(), "--version")
+	require.NoError(t, err)
+	require.Regexp(t, `^git version \S+`, output)
+	require.NotContains(t, output, "\n")
+
+	_, err = GitOutput(t.Context(), "no-such-command")
+	require.ErrorIs(t, err, ErrGitCommand)
+}
+
+func TestIsShallowRepository(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	origin := t.TempDir()
+	t.Chdir(origin)
+	t.Setenv("GIT_AUTHOR_NAME", "yadlfs")
+	t.Setenv("GIT_AUTHOR_EMAIL", "yadlfs@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "yadlfs")
+	t.Setenv("GIT_COMMITTER_EMAIL", "yadlfs@example.com")
+
+	out, err := exec.Command("git", "init", "-q").CombinedOutput()
+	require.NoError(t, err, string(out))
+
+	gitCommit(t, map[string]string{"a.bin": pointerContent(testPointerA, 1)})
+	gitCommit(t, map[string]string{"a.bin": pointerContent(testPointerB, 2)})
+
+	shallow, err := IsShallowRepository(t.Context())
+	require.NoError(t, err)
+	require.False(t, shallow)
+
+	clone := filepath.Join(t.TempDir(), "clone")
+	out, err = exec.Command("git", "clone", "-q", "--depth", "1", "file://"+origin, clone).CombinedOutput()
+	require.NoError(t, err, string(out))
+	t.Chdir(clone)
+
+	shallow, err = IsShallowRepository(t.Context())
+	require.NoError(t, err)
+	require.True(t, shallow)
+}
@@ -0,0 +1,87 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var ErrOpenLogFile = errors.New("failed to open log file")
+
+const redacted = "[REDACTED]"
+
+// signedURLPattern matches URL query strings, which hold signatures of the
+// upload/download hrefs handed out by Yandex Disk.
+var signedURLPattern = regexp.MustCompile(`(https?://[^\s"?]+)\?[^\s"]*`)
+
+// NewLogger creates a JSON logger writing to the configured log file.
+// stdout is the git-lfs protocol channel, so without a log file all records
+// are discarded. Secrets are removed from every record.
+func NewLogger(config *Config) (*slog.Logger, io.Closer, error) {
+	logFile := config.LogFile
+	if logFile == "" {
+		// the variable works even when the config comes from .yadlfs.yaml
+		logFile = os.Getenv("YADLFS_LOG")
+	}
+
+	if logFile == "" {
+		return slog.New(slog.DiscardHandler), io.NopCloser(nil), nil
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(config.LogLevel)); err != nil && config.LogLevel != "" {
+		return nil, nil, fmt.Errorf("%w: %w", ErrOpenLogFile, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(logFile), 0o755); err != nil { //nolint:mnd // rwxr-xr-x
+		return nil, nil, fmt.Errorf("%w: %w", ErrOpenLogFile, err)
+	}
+
+	file, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600) //nolint:mnd // rw-------
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrOpenLogFile, err)
+	}
+
+	handler := slog.NewJSONHandler(file, &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: newRedactor(config.YandexDiskOAuthToken, config.EncryptionPassphrase, config.S3SecretAccessKey),
+	})
+
+	return slog.New(handler).With("pid", os.Getpid()), file, nil
+}
+
+// newRedactor returns a slog.HandlerOptions.ReplaceAttr function removing the
+// given secrets and the signed parts of URLs from string and error values.
+func newRedactor(secrets ...string) func([]string, slog.Attr) slog.Attr {
+	replacements := make([]string, 0, 2*len(secrets)) //nolint:mnd // old, new pairs
+
+	for _, secret := range secrets {
+		if secret != "" {
+			replacements = append(replacements, secret, redacted)
+		}
+	}
+
+	replacer := strings.NewReplacer(replacements...)
+
+	redact := func(value string) string {
+		return signedURLPattern.ReplaceAllString(replacer.Replace(value), "$1?"+redacted)
+	}
+
+	return func(_ []string, attr slog.Attr) slog.Attr {
+		switch attr.Value.Kind() { //nolint:exhaustive // other kinds can't hold secrets
+		case slog.KindString:
+			attr.Value = slog.StringValue(redact(attr.Value.String()))
+		case slog.KindAny:
+			if err, ok := attr.Value.Any().(error); ok {
+				attr.Value = slog.StringValue(redact(err.Error()))
+			}
+		}
+
+		return attr
+	}
+}
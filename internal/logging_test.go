@@ -0,0 +1,97 @@
+//nolint:paralleltest
+package internal
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoggerRedactsSecrets(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "logs", "yadlfs.log")
+	config := &Config{
+		YandexDiskOAuthToken: "y0_secret_token",
+		LogFile:              logFile,
+		LogLevel:             "debug",
+	}
+
+	logger, closer, err := NewLogger(config)
+	require.NoError(t, err)
+
+	//nolint:err113
+	logger.Debug(
+		"request with OAuth y0_secret_token",
+		"oid", "0a5070",
+		"href", "https://downloader.disk.yandex.ru/disk/abc?sign=deadbeef&expires=1",
+		"error", errors.New(`Get "https://uploader.disk.yandex.net/upload?sign=cafe": timeout`),
+	)
+	require.NoError(t, closer.Close())
+
+	content, err := os.ReadFile(logFile)
+	require.NoError(t, err)
+	require.Contains(t, string(content), `"oid":"0a5070"`)
+	require.Contains(t, string(content), "https://downloader.disk.yandex.ru/disk/abc?[REDACTED]")
+	require.NotContains(t, string(content), "y0_secret_token")
+	require.NotContains(t, string(content), "deadbeef")
+	require.NotContains(t, string(content), "cafe")
+}
+
+func TestLoggerLevel(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "yadlfs.log")
+
+	logger, closer, err := NewLogger(&Config{LogFile: logFile, LogLevel: "warn"})
+	require.NoError(t, err)
+
+	logger.Info("hidden")
+	logger.Warn("visible")
+	require.NoError(t, closer.Close())
+
+	content, err := os.ReadFile(logFile)
+	require.NoError(t, err)
+	require.NotContains(t, string(content), "hidden")
+	require.Contains(t, string(content), "visible")
+
+	_, _, err = NewLogger(&Config{LogFile: logFile, LogLevel: "verbose"})
+	require.ErrorIs(t, err, ErrOpenLogFile)
+}
+
+func TestLoggerDisabled(t *testing.T) {
+	t.Setenv("YADLFS_LOG", "")
+
+	logger, closer, err := NewLogger(&Config{})
+	require.NoError(t, err)
+	require.False(t, logger.Enabled(t.Context(), 100))
+	require.NoError(t, closer.Close())
+}
+
+func TestLoggerRedactsS3Config(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "yadlfs.log")
+	config := &Config{
+		Backend:           BackendS3,
+		S3Bucket:          "bucket",
+		S3AccessKeyID:     "AKIDEXAMPLE",
+		S3SecretAccessKey: "s3_secret_key",
+		LogFile:           logFile,
+		LogLevel:          "debug",
+	}
+
+	logger, closer, err := NewLogger(config)
+	require.NoError(t, err)
+
+	//nolint:err113
+	logger.Debug(
+		"remote opened",
+		"config", config,
+		"error", errors.New("signing with s3_secret_key failed"),
+	)
+	require.NoError(t, closer.Close())
+
+	content, err := os.ReadFile(logFile)
+	require.NoError(t, err)
+	require.Contains(t, string(content), `"s3Bucket":"bucket"`)
+	require.Contains(t, string(content), `"s3SecretAccessKey":"[REDACTED]"`)
+	require.NotContains(t, string(content), "s3_secret_key")
+}
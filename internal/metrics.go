@@ -0,0 +1,201 @@
+package internal
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// httpDurationBuckets are the upper bounds of the HTTP latency histogram in
+// seconds. Storage requests stream whole objects, hence the long tail.
+//
+//nolint:gochecknoglobals // read-only lookup table
+var httpDurationBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 300}
+
+type httpSeriesKey struct {
+	method   string
+	endpoint string
+}
+
+type httpSeries struct {
+	buckets []int64
+	sum     float64
+	count   int64
+}
+
+// Metrics collects HTTP request latencies. Together with the session
+// summary they are exported in the OpenMetrics text format, so CI agents can
+// pick them up with the node_exporter textfile collector.
+type Metrics struct {
+	mu       sync.Mutex
+	requests map[httpSeriesKey]*httpSeries
+	statuses map[string]int64
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{
+		requests: map[httpSeriesKey]*httpSeries{},
+		statuses: map[string]int64{},
+	}
+}
+
+// ObserveRequest implements pkg.RequestObserver.
+func (m *Metrics) ObserveRequest(method, endpoint string, status int, duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := httpSeriesKey{method, endpoint}
+
+	series, ok := m.requests[key]
+	if !ok {
+		series = &httpSeries{buckets: make([]int64, len(httpDurationBuckets))}
+		m.requests[key] = series
+	}
+
+	seconds := duration.Seconds()
+	for i, bound := range httpDurationBuckets {
+		if seconds <= bound {
+			series.buckets[i]++
+		}
+	}
+
+	series.sum += seconds
+	series.count++
+
+	m.statuses[strconv.Itoa(status)]++
+}
+
+// WriteOpenMetrics writes the session metrics in the OpenMetrics text format.
+func (m *Metrics) WriteOpenMetrics(w io.Writer, summary SessionSummary) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var out bytes.Buffer
+
+	direction := string(summary.Operation)
+
+	errorCodes := map[string]int64{}
+
+	for _, transfer := range summary.Transfers {
+		if transfer.Error != "" {
+			errorCodes[strconv.FormatInt(transfer.Code, 10)]++
+		}
+	}
+
+	writeMetricHeader(&out, "yadlfs_transferred_bytes", "counter", "Object bytes transferred to or from the remote.")
+	fmt.Fprintf(&out, "yadlfs_transferred_bytes_total{direction=%q} %d\n", direction, summary.Bytes)
+
+	writeMetricHeader(&out, "yadlfs_objects", "counter", "Objects handled by the agent by result.")
+	fmt.Fprintf(&out, "yadlfs_objects_total{direction=%q,result=\"completed\"} %d\n", direction, summary.Completed)
+	fmt.Fprintf(&out, "yadlfs_objects_total{direction=%q,result=\"skipped\"} %d\n", direction, summary.Skipped)
+	fmt.Fprintf(&out, "yadlfs_objects_total{direction=%q,result=\"failed\"} %d\n", direction, summary.Failed)
+
+	writeMetricHeader(&out, "yadlfs_transfer_errors", "counter", "Failed transfers by the error code reported to git-lfs.")
+
+	for _, code := range sortedKeys(errorCodes) {
+		fmt.Fprintf(&out, "yadlfs_transfer_errors_total{code=%q} %d\n", code, errorCodes[code])
+	}
+
+	writeMetricHeader(&out, "yadlfs_cache_hits", "counter", "Objects served from the local cache.")
+	fmt.Fprintf(&out, "yadlfs_cache_hits_total %d\n", summary.Skipped)
+
+	writeMetricHeader(&out, "yadlfs_session_duration_seconds", "gauge", "Duration of the last session.")
+	fmt.Fprintf(&out, "yadlfs_session_duration_seconds %g\n", summary.Duration.Seconds())
+
+	writeMetricHeader(&out, "yadlfs_http_responses", "counter", "HTTP responses by status code, 0 means no response.")
+
+	for _, status := range sortedKeys(m.statuses) {
+		fmt.Fprintf(&out, "yadlfs_http_responses_total{status=%q} %d\n", status, m.statuses[status])
+	}
+
+	writeMetricHeader(&out, "yadlfs_http_request_duration_seconds", "histogram", "Yandex Disk HTTP request latency.")
+	m.writeHistograms(&out)
+
+	out.WriteString("# EOF\n")
+
+	if _, err := w.Write(out.Bytes()); err != nil {
+		return fmt.Errorf("failed to write metrics: %w", err)
+	}
+
+	return nil
+}
+
+func (m *Metrics) writeHistograms(out *bytes.Buffer) {
+	keys := make([]httpSeriesKey, 0, len(m.requests))
+	for key := range m.requests {
+		keys = append(keys, key)
+	}
+
+	slices.SortFunc(keys, func(a, b httpSeriesKey) int {
+		if a.endpoint != b.endpoint {
+			return cmp.Compare(a.endpoint, b.endpoint)
+		}
+
+		return cmp.Compare(a.method, b.method)
+	})
+
+	for _, key := range keys {
+		series := m.requests[key]
+		labels := fmt.Sprintf("endpoint=%q,method=%q", key.endpoint, key.method)
+
+		for i, bound := range httpDurationBuckets {
+			fmt.Fprintf(out, "yadlfs_http_request_duration_seconds_bucket{%s,le=\"%g\"} %d\n", labels, bound, series.buckets[i])
+		}
+
+		fmt.Fprintf(out, "yadlfs_http_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, series.count)
+		fmt.Fprintf(out, "yadlfs_http_request_duration_seconds_sum{%s} %g\n", labels, series.sum)
+		fmt.Fprintf(out, "yadlfs_http_request_duration_seconds_count{%s} %d\n", labels, series.count)
+	}
+}
+
+// WriteFile atomically replaces the metrics file, so the textfile collector
+// never reads a partially written one.
+func (m *Metrics) WriteFile(path string, summary SessionSummary) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create metrics file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := m.WriteOpenMetrics(tmp, summary); err != nil {
+		tmp.Close()
+
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write metrics: %w", err)
+	}
+
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil { //nolint:mnd // rw-r--r--
+		return fmt.Errorf("failed to write metrics: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write metrics: %w", err)
+	}
+
+	return nil
+}
+
+func writeMetricHeader(out *bytes.Buffer, name, metricType, help string) {
+	fmt.Fprintf(out, "# TYPE %s %s\n# HELP %s %s\n", name, metricType, name, help)
+}
+
+func sortedKeys(m map[string]int64) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	slices.Sort(keys)
+
+	return keys
+}
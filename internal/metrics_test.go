@@ -0,0 +1,52 @@
+package internal
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsOpenMetrics(t *testing.T) {
+	t.Parallel()
+
+	metrics := NewMetrics()
+	metrics.ObserveRequest("GET", "/resources/upload", 200, 80*time.Millisecond)
+	metrics.ObserveRequest("GET", "/resources/upload", 200, 3*time.Second)
+	metrics.ObserveRequest("PUT", "storage", 0, time.Second)
+
+	summary := SessionSummary{
+		Operation: OperationNameUpload,
+		Duration:  2 * time.Second,
+		Completed: 1,
+		Failed:    1,
+		Bytes:     1024,
+		Transfers: []TransferStats{
+			{OID: "a", Bytes: 1024},
+			{OID: "b", Code: ErrorCodeGeneric, Error: "upload failed"},
+		},
+	}
+
+	var out bytes.Buffer
+	require.NoError(t, metrics.WriteOpenMetrics(&out, summary))
+
+	text := out.String()
+	require.Contains(t, text, `yadlfs_transferred_bytes_total{direction="upload"} 1024`)
+	require.Contains(t, text, `yadlfs_objects_total{direction="upload",result="failed"} 1`)
+	require.Contains(t, text, `yadlfs_transfer_errors_total{code="0"} 1`)
+	require.Contains(t, text, `yadlfs_http_responses_total{status="200"} 2`)
+	require.Contains(t, text, `yadlfs_http_request_duration_seconds_bucket{endpoint="/resources/upload",method="GET",le="0.1"} 1`)
+	require.Contains(t, text, `yadlfs_http_request_duration_seconds_bucket{endpoint="/resources/upload",method="GET",le="5"} 2`)
+	require.Contains(t, text, `yadlfs_http_request_duration_seconds_count{endpoint="storage",method="PUT"} 1`)
+	require.True(t, bytes.HasSuffix(out.Bytes(), []byte("# EOF\n")))
+
+	path := filepath.Join(t.TempDir(), "yadlfs.prom")
+	require.NoError(t, metrics.WriteFile(path, summary))
+
+	written, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, text, string(written))
+}
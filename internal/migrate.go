@@ -0,0 +1,212 @@
+package internal
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrMigrationState    = errors.New("failed to access migration state")
+	ErrMigrationRoute    = errors.New("migration state belongs to another source or destination")
+	ErrMigrationChecksum = errors.New("migrated object doesn't match its oid")
+)
+
+const migrationRoutePrefix = "# route "
+
+// MigrationRoute identifies a migration by the storage locations of its
+// source and destination, so the state of one pair isn't used for another.
+func MigrationRoute(src, dst *Config) string {
+	return remoteLocation(src) + " -> " + remoteLocation(dst)
+}
+
+func remoteLocation(config *Config) string {
+	if config.Backend == BackendS3 {
+		return fmt.Sprintf("s3:%s/%s/%s", config.S3Endpoint, config.S3Bucket, strings.Trim(config.S3Prefix, "/"))
+	}
+
+	return "yandex:" + config.YandexDiskProjectFolder
+}
+
+// DefaultMigrationStatePath returns the state file of the route in folder.
+func DefaultMigrationStatePath(folder, route string) string {
+	sum := sha256.Sum256([]byte(route))
+
+	return filepath.Join(folder, "migrate-"+hex.EncodeToString(sum[:8])+".state")
+}
+
+// MigrationState remembers the objects already copied to the destination,
+// so an interrupted migration resumes where it stopped. The state file is
+// append-only with one OID per line, so a crash loses at most one record.
+// Its first line records the route, a state of another route is refused.
+type MigrationState struct {
+	mu   sync.Mutex
+	file *os.File
+	done map[string]bool
+}
+
+func OpenMigrationState(path, route string) (*MigrationState, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil { //nolint:mnd // rwxr-xr-x
+		return nil, fmt.Errorf("%w: %w", ErrMigrationState, err)
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644) //nolint:mnd // rw-r--r--
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMigrationState, err)
+	}
+
+	state := &MigrationState{file: file, done: map[string]bool{}}
+
+	if err := state.load(path, route); err != nil {
+		file.Close()
+
+		return nil, err
+	}
+
+	return state, nil
+}
+
+// load reads the copied objects, a new state file gets the route recorded.
+func (s *MigrationState) load(path, route string) error {
+	recorded := ""
+	empty := true
+
+	scanner := bufio.NewScanner(s.file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if empty && strings.HasPrefix(line, migrationRoutePrefix) {
+			recorded = strings.TrimPrefix(line, migrationRoutePrefix)
+		}
+
+		if IsOID(line) {
+			s.done[line] = true
+		}
+
+		empty = false
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("%w: %w", ErrMigrationState, err)
+	}
+
+	if empty {
+		if _, err := fmt.Fprintln(s.file, migrationRoutePrefix+route); err != nil {
+			return fmt.Errorf("%w: %w", ErrMigrationState, err)
+		}
+
+		return nil
+	}
+
+	if recorded != route {
+		return fmt.Errorf("%w: %s records %q, not %q", ErrMigrationRoute, path, recorded, route)
+	}
+
+	return nil
+}
+
+func (s *MigrationState) Done(oid string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.done[oid]
+}
+
+func (s *MigrationState) MarkDone(oid string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := fmt.Fprintln(s.file, oid); err != nil {
+		return fmt.Errorf("%w: %w", ErrMigrationState, err)
+	}
+
+	s.done[oid] = true
+
+	return nil
+}
+
+func (s *MigrationState) Close() error {
+	if err := s.file.Close(); err != nil {
+		return fmt.Errorf("%w: %w", ErrMigrationState, err)
+	}
+
+	return nil
+}
+
+// MigrateObject streams an object from one repository to another without
+// staging it on disk and verifies its content against the OID on the way.
+// A mismatch fails the last read of the stream, which aborts the upload, so
+// a corrupt source isn't stored under the OID. It returns the number of
+// object bytes copied, progress is called as they are read and may be nil.
+func MigrateObject(
+	ctx context.Context,
+	src, dst Repository,
+	oid string,
+	progress func(bytesSoFar, bytesSinceLast int64),
+) (int64, error) {
+	body, err := src.Download(ctx, oid)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrDownloadFailed, err)
+	}
+	defer body.Close()
+
+	verifier := &verifyingReader{r: body, hash: sha256.New(), oid: oid}
+
+	var size int64
+
+	reader := newByteCountingReader(verifier, func(bytesSoFar, bytesSinceLast int64) {
+		size = bytesSoFar
+
+		if progress != nil {
+			progress(bytesSoFar, bytesSinceLast)
+		}
+	})
+
+	if err := dst.Upload(ctx, oid, reader, true); err != nil {
+		return size, fmt.Errorf("%w: %w", ErrUploadFailed, err)
+	}
+
+	// the destination may stop reading before the end of the stream
+	if err := verifier.verify(); err != nil {
+		return size, err
+	}
+
+	return size, nil
+}
+
+// verifyingReader hashes the stream and fails at its end when the content
+// doesn't match the OID.
+type verifyingReader struct {
+	r    io.Reader
+	hash hash.Hash
+	oid  string
+}
+
+func (v *verifyingReader) Read(p []byte) (int, error) {
+	n, err := v.r.Read(p)
+	v.hash.Write(p[:n])
+
+	if errors.Is(err, io.EOF) {
+		if err := v.verify(); err != nil {
+			return n, err
+		}
+	}
+
+	return n, err //nolint:wrapcheck // io.Reader errors are returned as is
+}
+
+func (v *verifyingReader) verify() error {
+	if sum := hex.EncodeToString(v.hash.Sum(nil)); sum != v.oid {
+		return fmt.Errorf("%w: %s has sha256 %s", ErrMigrationChecksum, v.oid, sum)
+	}
+
+	return nil
+}
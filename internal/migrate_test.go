@@ -0,0 +1,118 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/alxarno/yadlfs/internal/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateObject(t *testing.T) {
+	t.Parallel()
+
+	content := []byte("object moved between remotes")
+	sum := sha256.Sum256(content)
+	oid := hex.EncodeToString(sum[:])
+
+	src := mocks.NewMemoryRepository()
+	src.Objects[oid] = content
+	src.Objects["corrupt"] = content
+
+	// the destination re-encodes objects with its own settings
+	dstBackend := mocks.NewMemoryRepository()
+	dst := NewEncryptedRepository(dstBackend, &EncryptionKey{raw: bytes.Repeat([]byte{3}, encryptionKeySize)})
+
+	size, err := MigrateObject(t.Context(), src, dst, oid, nil)
+	require.NoError(t, err)
+	require.Equal(t, int64(len(content)), size)
+	require.NotEqual(t, content, dstBackend.Objects[oid])
+
+	body, err := dst.Download(t.Context(), oid)
+	require.NoError(t, err)
+
+	migrated, err := io.ReadAll(body)
+	require.NoError(t, err)
+	require.Equal(t, content, migrated)
+
+	_, err = MigrateObject(t.Context(), src, dst, "corrupt", nil)
+	require.ErrorIs(t, err, ErrUploadFailed)
+	require.ErrorIs(t, err, ErrMigrationChecksum)
+
+	// the upload was aborted, nothing is stored under the oid
+	require.NotContains(t, dstBackend.Objects, "corrupt")
+
+	_, err = MigrateObject(t.Context(), src, dst, "missing", nil)
+	require.ErrorIs(t, err, ErrDownloadFailed)
+}
+
+func TestMigrationStateResume(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "state", "migrate.state")
+	oid := "3333333333333333333333333333333333333333333333333333333333333333"
+
+	state, err := OpenMigrationState(path, "a -> b")
+	require.NoError(t, err)
+	require.False(t, state.Done(oid))
+	require.NoError(t, state.MarkDone(oid))
+	require.True(t, state.Done(oid))
+	require.NoError(t, state.Close())
+
+	state, err = OpenMigrationState(path, "a -> b")
+	require.NoError(t, err)
+	require.True(t, state.Done(oid))
+	require.NoError(t, state.Close())
+
+	_, err = OpenMigrationState(path, "a -> c")
+	require.ErrorIs(t, err, ErrMigrationRoute)
+}
+
+func TestMigrationStatePerRoute(t *testing.T) {
+	t.Parallel()
+
+	source := &Config{Backend: BackendYandex, YandexDiskProjectFolder: "project"}
+	destinations := map[string]*Config{
+		"B": {Backend: BackendYandex, YandexDiskProjectFolder: "archive"},
+		"C": {Backend: BackendS3, S3Bucket: "bucket", S3Prefix: "project"},
+	}
+
+	src := mocks.NewMemoryRepository()
+
+	var oids []string
+
+	for _, content := range []string{"first", "second", "third"} {
+		sum := sha256.Sum256([]byte(content))
+		oid := hex.EncodeToString(sum[:])
+		src.Objects[oid] = []byte(content)
+		oids = append(oids, oid)
+	}
+
+	folder := t.TempDir()
+
+	// A to B, then A to C with the default state paths of the routes
+	for _, name := range []string{"B", "C"} {
+		dst := mocks.NewMemoryRepository()
+		route := MigrationRoute(source, destinations[name])
+
+		state, err := OpenMigrationState(DefaultMigrationStatePath(folder, route), route)
+		require.NoError(t, err)
+
+		for _, oid := range oids {
+			if state.Done(oid) {
+				continue
+			}
+
+			_, err := MigrateObject(t.Context(), src, dst, oid, nil)
+			require.NoError(t, err)
+			require.NoError(t, state.MarkDone(oid))
+		}
+
+		require.NoError(t, state.Close())
+		require.Len(t, dst.Objects, len(oids), name)
+	}
+}
@@ -0,0 +1,47 @@
+package mocks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+var ErrObjectNotFound = errors.New("object not found")
+
+type MemoryRepository struct {
+	mu      sync.Mutex
+	Objects map[string][]byte
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{Objects: map[string][]byte{}}
+}
+
+func (m *MemoryRepository) Upload(_ context.Context, filePath string, r io.Reader, _ bool) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to read upload: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.Objects[filePath] = data
+
+	return nil
+}
+
+func (m *MemoryRepository) Download(_ context.Context, path string) (io.ReadCloser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	data, ok := m.Objects[path]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrObjectNotFound, path)
+	}
+
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
@@ -0,0 +1,91 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/alxarno/yadlfs/pkg"
+)
+
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// QuotaSource reports the space of the remote account, like
+// pkg.YandexDiskClient.
+type QuotaSource interface {
+	Disk(ctx context.Context) (*pkg.DiskInfo, error)
+}
+
+// quota tracks the free space left for the uploads of a session. It's read
+// once at init, so objects which can't fit are refused before a byte is sent
+// instead of failing with 507 at the end of a long upload.
+type quota struct {
+	source QuotaSource
+	mu     sync.Mutex
+	free   int64
+	known  bool
+}
+
+// WithQuota makes the controller check the free space of the remote at init
+// of upload sessions and refuse objects which don't fit.
+func WithQuota(source QuotaSource) ControllerOption {
+	return func(s *Controller) {
+		s.quota = &quota{source: source}
+	}
+}
+
+func (q *quota) refresh(ctx context.Context) error {
+	if q == nil {
+		return nil
+	}
+
+	info, err := q.source.Disk(ctx)
+	if err != nil {
+		return err //nolint:wrapcheck // the source wraps it
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.free = info.FreeSpace()
+	q.known = true
+
+	return nil
+}
+
+// reserve takes the space of an object out of the free space. Uploads
+// replacing an existing object are counted in full, the check errs on the
+// safe side.
+func (q *quota) reserve(size int64) error {
+	if q == nil {
+		return nil
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if !q.known {
+		return nil
+	}
+
+	if size > q.free {
+		return fmt.Errorf("%w: object needs %d bytes, %d bytes are free", ErrQuotaExceeded, size, q.free)
+	}
+
+	q.free -= size
+
+	return nil
+}
+
+// release returns the space of a failed upload.
+func (q *quota) release(size int64) {
+	if q == nil {
+		return
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.free += size
+}
@@ -0,0 +1,70 @@
+package internal
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alxarno/yadlfs/internal/mocks"
+	"github.com/alxarno/yadlfs/pkg"
+	"github.com/stretchr/testify/require"
+)
+
+type staticQuota pkg.DiskInfo
+
+func (q staticQuota) Disk(context.Context) (*pkg.DiskInfo, error) {
+	info := pkg.DiskInfo(q)
+
+	return &info, nil
+}
+
+func TestQuotaExceeded(t *testing.T) {
+	t.Parallel()
+
+	folder := t.TempDir()
+	small, large := filepath.Join(folder, "small"), filepath.Join(folder, "large")
+
+	require.NoError(t, os.WriteFile(small, make([]byte, 600), 0o600))
+	require.NoError(t, os.WriteFile(large, make([]byte, 600), 0o600))
+
+	backend := mocks.NewMemoryRepository()
+	messages := make(chan DialMessage)
+	controller := NewController(backend, folder, messages, WithQuota(staticQuota{TotalSpace: 2000, UsedSpace: 1000}))
+
+	input := strings.Join([]string{
+		`{ "event": "init", "operation": "upload", "remote": "origin", "concurrent": true, "concurrenttransfers": 1 }`,
+		fmt.Sprintf(`{ "event": "upload", "oid": "small", "size": 600, "path": %q }`, small),
+		fmt.Sprintf(`{ "event": "upload", "oid": "large", "size": 600, "path": %q }`, large),
+		`{ "event": "terminate" }`,
+	}, "\n")
+
+	outR, outW := io.Pipe()
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	go NewDial(outW, messages).ListenAndServe(ctx)
+	go func() {
+		_ = NewDispatcher(strings.NewReader(input), controller).ListenAndServe(ctx)
+	}()
+
+	var completes []string
+
+	scanner := bufio.NewScanner(outR)
+	for len(completes) < 2 && scanner.Scan() {
+		if strings.Contains(scanner.Text(), `"complete"`) {
+			completes = append(completes, scanner.Text())
+		}
+	}
+
+	require.Equal(t, `{"event":"complete","oid":"small"}`, completes[0])
+	require.Contains(t, completes[1], `"code":507`)
+	require.Contains(t, completes[1], "quota exceeded")
+	require.Contains(t, backend.Objects, "small")
+	require.NotContains(t, backend.Objects, "large")
+}
@@ -0,0 +1,152 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/alxarno/yadlfs/internal/mocks"
+	"github.com/alxarno/yadlfs/pkg"
+	"github.com/stretchr/testify/require"
+)
+
+// rangedRepository serves ranges of the objects stored in memory.
+type rangedRepository struct {
+	*mocks.MemoryRepository
+
+	unsupported bool
+	requests    atomic.Int64
+}
+
+func (r *rangedRepository) DownloadURL(_ context.Context, filePath string) (string, error) {
+	return filePath, nil
+}
+
+func (r *rangedRepository) DownloadRange(_ context.Context, href string, offset, length int64) (io.ReadCloser, error) {
+	r.requests.Add(1)
+
+	if r.unsupported {
+		return nil, pkg.ErrRangesNotSupported
+	}
+
+	body, err := r.Download(context.Background(), href)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // test helper
+	}
+
+	data, _ := io.ReadAll(body)
+	end := min(offset+length, int64(len(data)))
+
+	return io.NopCloser(bytes.NewReader(data[min(offset, end):end])), nil
+}
+
+// runDownload downloads a single object and returns the complete message and
+// the last reported progress.
+func runDownload(t *testing.T, controller *Controller, messages chan DialMessage, oid string, size int) (string, int64) {
+	t.Helper()
+
+	input := strings.Join([]string{
+		`{ "event": "init", "operation": "download", "remote": "origin", "concurrent": true, "concurrenttransfers": 1 }`,
+		fmt.Sprintf(`{ "event": "download", "oid": "%s", "size": %d, "path": "" }`, oid, size),
+		`{ "event": "terminate" }`,
+	}, "\n")
+
+	outR, outW := io.Pipe()
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	go NewDial(outW, messages).ListenAndServe(ctx)
+	go func() {
+		_ = NewDispatcher(strings.NewReader(input), controller).ListenAndServe(ctx)
+	}()
+
+	var bytesSoFar int64
+
+	scanner := bufio.NewScanner(outR)
+	for scanner.Scan() {
+		var progress ProgressMessage
+
+		switch {
+		case strings.Contains(scanner.Text(), `"complete"`):
+			return scanner.Text(), bytesSoFar
+		case strings.Contains(scanner.Text(), `"progress"`):
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &progress))
+			bytesSoFar = progress.BytesSoFar
+		}
+	}
+
+	t.Fatal("no complete message")
+
+	return "", 0
+}
+
+func TestRangedDownload(t *testing.T) {
+	t.Parallel()
+
+	data := make([]byte, 10_000)
+	_, err := rand.Read(data)
+	require.NoError(t, err)
+
+	sum := sha256.Sum256(data)
+	oid := hex.EncodeToString(sum[:])
+
+	tests := []struct {
+		name        string
+		unsupported bool
+		corrupt     bool
+		compressed  bool
+		requests    int64
+	}{
+		{"Parts", false, false, false, 4},
+		{"RangesNotSupported", true, false, false, 1},
+		// the ranges don't match the oid, so the object is streamed again
+		{"Checksum", false, true, false, 4},
+		{"Compressed", false, false, true, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			backend := &rangedRepository{MemoryRepository: mocks.NewMemoryRepository(), unsupported: tt.unsupported}
+			backend.Objects[oid] = data
+			expected := data
+
+			var warehouse Repository = backend
+
+			switch {
+			case tt.corrupt:
+				expected = bytes.Repeat([]byte{1}, len(data))
+				backend.Objects[oid] = expected
+			case tt.compressed:
+				warehouse = NewCompressedRepository(backend, compressionGzip)
+				require.NoError(t, warehouse.Upload(t.Context(), oid, bytes.NewReader(data), true))
+			}
+
+			folder := t.TempDir()
+			messages := make(chan DialMessage)
+			controller := NewController(warehouse, folder, messages, WithRangedDownloads(backend, 4, 1024))
+
+			complete, bytesSoFar := runDownload(t, controller, messages, oid, len(data))
+			require.Contains(t, complete, fmt.Sprintf(`{"event":"complete","oid":"%s","path":`, oid))
+			require.Equal(t, tt.requests, backend.requests.Load())
+			require.Equal(t, int64(len(data)), bytesSoFar)
+
+			downloaded, err := os.ReadFile(filepath.Join(folder, oid))
+			require.NoError(t, err)
+			require.Equal(t, expected, downloaded)
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package internal
+
+// WrapRepository stacks the optional encryption and compression layers
+// described by the config on top of the storage backend.
+func WrapRepository(config *Config, backend Repository) (Repository, error) {
+	encryptionKey, err := LoadEncryptionKey(config)
+	if err != nil {
+		return nil, err
+	}
+
+	compression, err := ParseCompression(config.Compression)
+	if err != nil {
+		return nil, err
+	}
+
+	warehouse := backend
+	if encryptionKey != nil {
+		warehouse = NewEncryptedRepository(warehouse, encryptionKey)
+	}
+
+	// without compression objects are stored as is, the marker of the wrapper
+	// would make plain objects starting with it differ from their pointers
+	if compression == compressionNone {
+		return warehouse, nil
+	}
+
+	return NewCompressedRepository(warehouse, compression), nil
+}
@@ -0,0 +1,237 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	"github.com/alxarno/yadlfs/pkg"
+)
+
+var (
+	ErrUploadState       = errors.New("failed to access upload state")
+	ErrUploadStateSource = errors.New("object is shorter than its interrupted upload")
+)
+
+// rangesUnsupportedMarker is created in the state folder once the storage
+// rejected a chunk, so later sessions don't probe it again.
+const rangesUnsupportedMarker = "ranges-unsupported"
+
+// ChunkedBackend is a storage backend whose upload hrefs accept
+// Content-Range chunks, like pkg.YandexDiskClient.
+type ChunkedBackend interface {
+	Repository
+	UploadURL(ctx context.Context, filePath string, overwrite bool) (string, error)
+	UploadRange(ctx context.Context, href string, r io.Reader, offset, length, size int64) error
+	Delete(ctx context.Context, filePath string, permanently bool) error
+}
+
+// uploadState is an interrupted upload: the href the object goes to and the
+// number of bytes the storage acknowledged.
+type uploadState struct {
+	Href   string `json:"href"`
+	Offset int64  `json:"offset"`
+}
+
+// ResumableRepository uploads objects bigger than a chunk in Content-Range
+// chunks and remembers the acknowledged offset, so an upload interrupted by
+// a network failure continues where it stopped on the next attempt.
+//
+// The uploaded stream must be the same on every attempt, so it only wraps
+// backends storing plain objects: encrypted streams differ on every upload.
+type ResumableRepository struct {
+	ChunkedBackend
+
+	folder    string
+	chunkSize int64
+	// unsupported is set once the storage rejected a chunk, the following
+	// uploads go with a single request right away
+	unsupported atomic.Bool
+}
+
+func NewResumableRepository(backend ChunkedBackend, folder string, chunkSize int64) *ResumableRepository {
+	repository := &ResumableRepository{ChunkedBackend: backend, folder: folder, chunkSize: chunkSize}
+
+	if _, err := os.Stat(filepath.Join(folder, rangesUnsupportedMarker)); err == nil {
+		repository.unsupported.Store(true)
+	}
+
+	return repository
+}
+
+func (r *ResumableRepository) Upload(ctx context.Context, filePath string, reader io.Reader, overwrite bool) error {
+	if r.unsupported.Load() {
+		return r.ChunkedBackend.Upload(ctx, filePath, reader, overwrite) //nolint:wrapcheck // the backend wraps it
+	}
+
+	source := bufio.NewReader(reader)
+
+	state, err := r.loadState(filePath)
+	if err != nil {
+		return err
+	}
+
+	if state.Offset > 0 {
+		if _, err := io.CopyN(io.Discard, source, state.Offset); err != nil {
+			r.removeState(filePath)
+
+			return fmt.Errorf("%w: %w", ErrUploadStateSource, err)
+		}
+	}
+
+	var chunk bytes.Buffer
+
+	for {
+		chunk.Reset()
+
+		if _, err := io.CopyN(&chunk, source, r.chunkSize); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("%w: %w", ErrCopyData, err)
+		}
+
+		length := int64(chunk.Len())
+		final := length < r.chunkSize
+
+		if !final {
+			if _, err := source.Peek(1); errors.Is(err, io.EOF) {
+				final = true
+			}
+		}
+
+		switch {
+		case final && state.Href == "":
+			// objects fitting into a single chunk have nothing to resume
+			return r.ChunkedBackend.Upload(ctx, filePath, &chunk, overwrite) //nolint:wrapcheck // the backend wraps it
+		case final && length == 0:
+			// the last chunk was acknowledged, but the state wasn't removed
+			r.removeState(filePath)
+
+			return nil
+		case state.Href == "":
+			if state.Href, err = r.UploadURL(ctx, filePath, overwrite); err != nil {
+				return err //nolint:wrapcheck // the backend wraps it
+			}
+		}
+
+		size := int64(-1)
+		if final {
+			size = state.Offset + length
+		}
+
+		err = r.UploadRange(ctx, state.Href, bytes.NewReader(chunk.Bytes()), state.Offset, length, size)
+
+		switch {
+		case errors.Is(err, pkg.ErrRangesNotSupported) && state.Offset == 0:
+			r.markUnsupported()
+
+			// the chunk already read from the source goes first
+			return r.uploadWhole(ctx, filePath, io.MultiReader(&chunk, source), overwrite)
+		case errors.Is(err, pkg.ErrRangesNotSupported), errors.Is(err, pkg.ErrUploadURLExpired):
+			// the next attempt starts over with a new href
+			r.removeState(filePath)
+
+			return err //nolint:wrapcheck // the backend wraps it
+		case err != nil:
+			return err //nolint:wrapcheck // the backend wraps it
+		}
+
+		state.Offset += length
+
+		if final {
+			r.removeState(filePath)
+
+			return nil
+		}
+
+		if err := r.saveState(filePath, state); err != nil {
+			return err
+		}
+	}
+}
+
+// uploadWhole uploads the object with a single request after the storage
+// rejected a chunk. The storage may have stored the chunk as the whole
+// object, so a failed upload removes it instead of leaving it truncated.
+func (r *ResumableRepository) uploadWhole(ctx context.Context, filePath string, reader io.Reader, overwrite bool) error {
+	err := r.ChunkedBackend.Upload(ctx, filePath, reader, overwrite)
+	if err == nil {
+		return nil
+	}
+
+	// the transfer may fail because it was canceled, the removal must not
+	deleteErr := r.Delete(context.WithoutCancel(ctx), filePath, true)
+	if deleteErr != nil && !errors.Is(deleteErr, pkg.ErrResourceNotFound) {
+		return errors.Join(err, deleteErr)
+	}
+
+	return err //nolint:wrapcheck // the backend wraps it
+}
+
+// markUnsupported remembers for this and later sessions that the storage
+// doesn't accept chunks. Failing to save it only costs a probe next time.
+func (r *ResumableRepository) markUnsupported() {
+	r.unsupported.Store(true)
+
+	if err := os.MkdirAll(r.folder, 0o755); err == nil { //nolint:mnd // rwxr-xr-x
+		_ = os.WriteFile(filepath.Join(r.folder, rangesUnsupportedMarker), nil, 0o600) //nolint:mnd // rw-------
+	}
+}
+
+func (r *ResumableRepository) statePath(filePath string) string {
+	return filepath.Join(r.folder, filepath.Base(filePath)+".json")
+}
+
+func (r *ResumableRepository) loadState(filePath string) (uploadState, error) {
+	var state uploadState
+
+	data, err := os.ReadFile(r.statePath(filePath))
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	} else if err != nil {
+		return state, fmt.Errorf("%w: %w", ErrUploadState, err)
+	}
+
+	// a corrupted state only costs the upload progress
+	if err := json.Unmarshal(data, &state); err != nil || state.Href == "" || state.Offset < 0 {
+		return uploadState{}, nil
+	}
+
+	return state, nil
+}
+
+// saveState replaces the state atomically, a crash leaves either the old or
+// the new offset, both of which are safe to resume from.
+func (r *ResumableRepository) saveState(filePath string, state uploadState) error {
+	if err := os.MkdirAll(r.folder, 0o755); err != nil { //nolint:mnd // rwxr-xr-x
+		return fmt.Errorf("%w: %w", ErrUploadState, err)
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrUploadState, err)
+	}
+
+	path := r.statePath(filePath)
+	tmp := path + ".tmp"
+
+	// the href grants write access to the object, so the state is private
+	if err := os.WriteFile(tmp, data, 0o600); err != nil { //nolint:mnd // rw-------
+		return fmt.Errorf("%w: %w", ErrUploadState, err)
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("%w: %w", ErrUploadState, err)
+	}
+
+	return nil
+}
+
+func (r *ResumableRepository) removeState(filePath string) {
+	_ = os.Remove(r.statePath(filePath))
+}
@@ -0,0 +1,194 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/alxarno/yadlfs/internal/mocks"
+	"github.com/alxarno/yadlfs/pkg"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	errChunkFailed  = errors.New("chunk failed")
+	errUploadFailed = errors.New("upload failed")
+)
+
+// chunkedRepository stores chunks in memory and fails the chunk at failAt
+// once. When unsupported, it stores the first chunk as the whole object,
+// like a storage ignoring Content-Range.
+type chunkedRepository struct {
+	*mocks.MemoryRepository
+
+	mu          sync.Mutex
+	unsupported bool
+	failUpload  bool
+	failAt      int64
+	ranges      [][2]int64
+	sessions    map[string][]byte
+	probes      int
+}
+
+func newChunkedRepository() *chunkedRepository {
+	return &chunkedRepository{
+		MemoryRepository: mocks.NewMemoryRepository(),
+		failAt:           -1,
+		sessions:         map[string][]byte{},
+	}
+}
+
+func (c *chunkedRepository) UploadURL(_ context.Context, filePath string, _ bool) (string, error) {
+	return "href/" + filePath, nil
+}
+
+func (c *chunkedRepository) UploadRange(ctx context.Context, href string, r io.Reader, offset, length, size int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.unsupported {
+		c.probes++
+		data, _ := io.ReadAll(r)
+		_ = c.MemoryRepository.Upload(ctx, href[len("href/"):], bytes.NewReader(data), true)
+
+		return pkg.ErrRangesNotSupported
+	}
+
+	if offset == c.failAt {
+		c.failAt = -1
+
+		return errChunkFailed
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err //nolint:wrapcheck // test helper
+	}
+
+	if int64(len(data)) != length || int64(len(c.sessions[href])) != offset {
+		return fmt.Errorf("unexpected chunk %d+%d", offset, length) //nolint:err113 // test helper
+	}
+
+	c.ranges = append(c.ranges, [2]int64{offset, length})
+	c.sessions[href] = append(c.sessions[href], data...)
+
+	if size >= 0 {
+		return c.MemoryRepository.Upload(ctx, href[len("href/"):], bytes.NewReader(c.sessions[href]), true)
+	}
+
+	return nil
+}
+
+func (c *chunkedRepository) Upload(ctx context.Context, filePath string, r io.Reader, overwrite bool) error {
+	if c.failUpload {
+		return errUploadFailed
+	}
+
+	return c.MemoryRepository.Upload(ctx, filePath, r, overwrite) //nolint:wrapcheck // test helper
+}
+
+func (c *chunkedRepository) Delete(_ context.Context, filePath string, _ bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.Objects[filePath]; !ok {
+		return pkg.ErrResourceNotFound
+	}
+
+	delete(c.Objects, filePath)
+
+	return nil
+}
+
+func TestResumableRepositorySmallObject(t *testing.T) {
+	t.Parallel()
+
+	backend := newChunkedRepository()
+	repository := NewResumableRepository(backend, t.TempDir(), 1024)
+
+	data := []byte("small object")
+	require.NoError(t, repository.Upload(t.Context(), "oid", bytes.NewReader(data), true))
+	require.Equal(t, data, backend.Objects["oid"])
+	require.Empty(t, backend.ranges)
+}
+
+func TestResumableRepositoryResume(t *testing.T) {
+	t.Parallel()
+
+	data := make([]byte, 4096)
+	_, err := rand.Read(data)
+	require.NoError(t, err)
+
+	folder := t.TempDir()
+	backend := newChunkedRepository()
+	backend.failAt = 2048
+	repository := NewResumableRepository(backend, folder, 1024)
+
+	err = repository.Upload(t.Context(), "oid", bytes.NewReader(data), true)
+	require.ErrorIs(t, err, errChunkFailed)
+	require.FileExists(t, filepath.Join(folder, "oid.json"))
+
+	require.NoError(t, repository.Upload(t.Context(), "oid", bytes.NewReader(data), true))
+	require.Equal(t, data, backend.Objects["oid"])
+	require.Equal(t, [][2]int64{{0, 1024}, {1024, 1024}, {2048, 1024}, {3072, 1024}}, backend.ranges)
+	require.NoFileExists(t, filepath.Join(folder, "oid.json"))
+}
+
+func TestResumableRepositoryRangesNotSupported(t *testing.T) {
+	t.Parallel()
+
+	data := make([]byte, 3000)
+	_, err := rand.Read(data)
+	require.NoError(t, err)
+
+	folder := t.TempDir()
+	backend := newChunkedRepository()
+	backend.unsupported = true
+	repository := NewResumableRepository(backend, folder, 1024)
+
+	require.NoError(t, repository.Upload(t.Context(), "oid", bytes.NewReader(data), true))
+	require.Equal(t, data, backend.Objects["oid"])
+	require.True(t, repository.unsupported.Load())
+
+	// the next session doesn't probe the storage again
+	repository = NewResumableRepository(backend, folder, 1024)
+	require.NoError(t, repository.Upload(t.Context(), "other", bytes.NewReader(data), true))
+	require.Equal(t, data, backend.Objects["other"])
+	require.Equal(t, 1, backend.probes)
+}
+
+func TestResumableRepositoryFallbackFailureRemovesObject(t *testing.T) {
+	t.Parallel()
+
+	backend := newChunkedRepository()
+	backend.unsupported = true
+	backend.failUpload = true
+	repository := NewResumableRepository(backend, t.TempDir(), 1024)
+
+	err := repository.Upload(t.Context(), "oid", bytes.NewReader(make([]byte, 3000)), true)
+	require.ErrorIs(t, err, errUploadFailed)
+
+	// the storage kept the first chunk as the object, it's truncated
+	require.NotContains(t, backend.Objects, "oid")
+}
+
+func TestResumableRepositoryCorruptedState(t *testing.T) {
+	t.Parallel()
+
+	folder := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(folder, "oid.json"), []byte("{"), 0o600))
+
+	data := make([]byte, 2500)
+	backend := newChunkedRepository()
+	repository := NewResumableRepository(backend, folder, 1024)
+
+	require.NoError(t, repository.Upload(t.Context(), "oid", bytes.NewReader(data), true))
+	require.Equal(t, data, backend.Objects["oid"])
+}
@@ -0,0 +1,53 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidSize = errors.New("invalid size")
+
+//nolint:gochecknoglobals // read-only lookup table
+var sizeUnits = []struct {
+	suffix string
+	value  int64
+}{
+	{"TiB", 1 << 40}, {"GiB", 1 << 30}, {"MiB", 1 << 20}, {"KiB", 1 << 10},
+	{"T", 1 << 40}, {"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10},
+	{"B", 1},
+}
+
+// ParseSize parses sizes like `512`, `100MiB` or `10G`.
+func ParseSize(value string) (int64, error) {
+	value = strings.TrimSpace(value)
+
+	for _, unit := range sizeUnits {
+		if number, ok := strings.CutSuffix(value, unit.suffix); ok {
+			size, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+			if err != nil || size < 0 {
+				return 0, fmt.Errorf("%w: %s", ErrInvalidSize, value)
+			}
+
+			return int64(size * float64(unit.value)), nil
+		}
+	}
+
+	size, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || size < 0 {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidSize, value)
+	}
+
+	return size, nil
+}
+
+func FormatSize(size int64) string {
+	for _, unit := range sizeUnits[:4] {
+		if size >= unit.value {
+			return fmt.Sprintf("%.1f %s", float64(size)/float64(unit.value), unit.suffix)
+		}
+	}
+
+	return fmt.Sprintf("%d B", size)
+}
@@ -0,0 +1,98 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// TransferStats describes the outcome of a single transfer.
+type TransferStats struct {
+	OID      string        `json:"oid"`
+	Size     int64         `json:"size"`
+	Bytes    int64         `json:"bytes"`
+	Duration time.Duration `json:"duration"`
+	Cached   bool          `json:"cached,omitempty"`
+	Code     int64         `json:"code,omitempty"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// SessionSummary aggregates the transfers of a session.
+type SessionSummary struct {
+	Operation  OperationName   `json:"operation"`
+	Started    time.Time       `json:"started"`
+	Duration   time.Duration   `json:"duration"`
+	Objects    int             `json:"objects"`
+	Completed  int             `json:"completed"`
+	Skipped    int             `json:"skipped"`
+	Failed     int             `json:"failed"`
+	Bytes      int64           `json:"bytes"`
+	Throughput float64         `json:"throughput"`
+	Transfers  []TransferStats `json:"transfers"`
+}
+
+// SessionStats collects per-transfer statistics of a git-lfs session.
+type SessionStats struct {
+	mu        sync.Mutex
+	started   time.Time
+	transfers []TransferStats
+}
+
+func NewSessionStats() *SessionStats {
+	return &SessionStats{started: time.Now()}
+}
+
+func (s *SessionStats) record(transfer TransferStats) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.transfers = append(s.transfers, transfer)
+}
+
+// Summary aggregates the recorded transfers. Objects served from the local
+// cache are counted as skipped and don't contribute to the throughput.
+func (s *SessionStats) Summary(operation OperationName) SessionSummary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	summary := SessionSummary{
+		Operation: operation,
+		Started:   s.started,
+		Duration:  time.Since(s.started),
+		Objects:   len(s.transfers),
+		Transfers: append([]TransferStats{}, s.transfers...),
+	}
+
+	for _, transfer := range s.transfers {
+		switch {
+		case transfer.Error != "":
+			summary.Failed++
+		case transfer.Cached:
+			summary.Skipped++
+		default:
+			summary.Completed++
+			summary.Bytes += transfer.Bytes
+		}
+	}
+
+	if seconds := summary.Duration.Seconds(); seconds > 0 {
+		summary.Throughput = float64(summary.Bytes) / seconds
+	}
+
+	return summary
+}
+
+func writeSessionSummary(path string, summary SessionSummary) error {
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal session summary: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0o644); err != nil { //nolint:gosec,mnd // rw-r--r--
+		return fmt.Errorf("failed to write session summary: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionStatsSummary(t *testing.T) {
+	t.Parallel()
+
+	stats := NewSessionStats()
+	stats.started = time.Now().Add(-2 * time.Second)
+
+	stats.record(TransferStats{OID: "a", Size: 100, Bytes: 100, Duration: time.Second})
+	stats.record(TransferStats{OID: "b", Size: 300, Bytes: 300, Duration: time.Second})
+	stats.record(TransferStats{OID: "c", Size: 50, Bytes: 50, Cached: true})
+	stats.record(TransferStats{OID: "d", Size: 70, Bytes: 10, Error: "download failed"})
+
+	summary := stats.Summary(OperationNameDownload)
+	require.Equal(t, OperationNameDownload, summary.Operation)
+	require.Equal(t, 4, summary.Objects)
+	require.Equal(t, 2, summary.Completed)
+	require.Equal(t, 1, summary.Skipped)
+	require.Equal(t, 1, summary.Failed)
+	require.Equal(t, int64(400), summary.Bytes)
+	require.InDelta(t, 200, summary.Throughput, 10)
+	require.Len(t, summary.Transfers, 4)
+}
+
+func TestControllerWritesStatsFile(t *testing.T) {
+	t.Parallel()
+
+	tempDir := t.TempDir()
+	statsFile := filepath.Join(tempDir, "stats.json")
+	objectPath := filepath.Join(tempDir, "object.bin")
+	require.NoError(t, os.WriteFile(objectPath, []byte("stats"), 0o600))
+
+	messages := make(chan DialMessage)
+	controller := NewController(NewFilesystemRepository(filepath.Join(tempDir, "remote")), tempDir, messages, WithStatsFile(statsFile))
+
+	input := strings.Join([]string{
+		`{ "event": "init", "operation": "upload", "remote": "origin", "concurrent": true, "concurrenttransfers": 2 }`,
+		`{ "event": "upload", "oid": "ok", "size": 5, "path": "` + objectPath + `" }`,
+		`{ "event": "upload", "oid": "missing", "size": 5, "path": "` + filepath.Join(tempDir, "missing") + `" }`,
+		`{ "event": "terminate" }`,
+	}, "\n")
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	go NewDial(io.Discard, messages).ListenAndServe(ctx)
+
+	err := NewDispatcher(strings.NewReader(input), controller).ListenAndServe(ctx)
+	require.ErrorIs(t, err, io.EOF)
+
+	data, err := os.ReadFile(statsFile)
+	require.NoError(t, err)
+
+	var summary SessionSummary
+	require.NoError(t, json.Unmarshal(data, &summary))
+	require.Equal(t, OperationNameUpload, summary.Operation)
+	require.Equal(t, 1, summary.Completed)
+	require.Equal(t, 1, summary.Failed)
+	require.Equal(t, int64(5), summary.Bytes)
+}
@@ -0,0 +1,230 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	progressBarWidth   = 24
+	progressRedrawRate = 100 * time.Millisecond
+	progressLogRate    = 10 * time.Second
+	progressOIDLength  = 12
+)
+
+// IsTerminal reports whether w is an interactive terminal rather than a
+// pipe or a file.
+func IsTerminal(w io.Writer) bool {
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+
+	info, err := file.Stat()
+
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+type objectProgress struct {
+	oid   string
+	size  int64
+	bytes int64
+}
+
+// TerminalProgress renders the controller messages of the standalone
+// commands for humans. On a terminal it redraws a bar per running object
+// and a footer with the overall throughput and ETA, otherwise it prints a
+// plain line per finished object.
+type TerminalProgress struct {
+	mu          sync.Mutex
+	w           io.Writer
+	interactive bool
+	now         func() time.Time
+	start       time.Time
+	lastDraw    time.Time
+	// drawn is the number of lines of the last redraw, they are replaced by
+	// the next one
+	drawn int
+
+	sizes      map[string]int64
+	running    []*objectProgress
+	totalBytes int64
+	bytes      int64
+	done       int
+	failed     int
+}
+
+// NewTerminalProgress creates a renderer for the given transfers, their
+// sizes are used for the ETA, zero means unknown.
+func NewTerminalProgress(w io.Writer, interactive bool, transfers []Transfer) *TerminalProgress {
+	progress := &TerminalProgress{
+		w:           w,
+		interactive: interactive,
+		now:         time.Now,
+		sizes:       make(map[string]int64, len(transfers)),
+	}
+
+	for _, transfer := range transfers {
+		progress.sizes[transfer.OID] = transfer.Size
+		progress.totalBytes += transfer.Size
+	}
+
+	progress.start = progress.now()
+	progress.lastDraw = progress.start
+
+	return progress
+}
+
+// Message consumes a controller message, it's safe for concurrent use.
+func (p *TerminalProgress) Message(msg DialMessage) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch msg := msg.(type) {
+	case ProgressMessage:
+		object := p.object(msg.OID)
+		object.bytes = msg.BytesSoFar
+		p.bytes += msg.BytesSinceLast
+
+		switch sinceDraw := p.now().Sub(p.lastDraw); {
+		case p.interactive && sinceDraw >= progressRedrawRate:
+			p.redraw()
+		case !p.interactive && sinceDraw >= progressLogRate:
+			// long transfers still show up in CI logs
+			fmt.Fprintln(p.w, p.footer())
+			p.lastDraw = p.now()
+		}
+	case CompleteMessage:
+		p.done++
+		p.finishObject(msg.OID, fmt.Sprintf("%s  %s", msg.OID, FormatSize(p.objectSize(msg.OID))))
+	case CompleteErrorMessage:
+		p.failed++
+		p.finishObject(msg.OID, fmt.Sprintf("%s  failed: %s", msg.OID, msg.Error.Message))
+	}
+}
+
+// Finish prints the summary and returns the number of finished and failed
+// objects.
+func (p *TerminalProgress) Finish(verb string) (int, int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.clear()
+
+	elapsed := p.now().Sub(p.start)
+	fmt.Fprintf(
+		p.w, "%s %d objects (%s) in %s, %d failed\n",
+		verb, p.done, FormatSize(p.bytes), elapsed.Round(time.Second), p.failed,
+	)
+
+	return p.done, p.failed
+}
+
+func (p *TerminalProgress) object(oid string) *objectProgress {
+	for _, object := range p.running {
+		if object.oid == oid {
+			return object
+		}
+	}
+
+	object := &objectProgress{oid: oid, size: p.sizes[oid]}
+	p.running = append(p.running, object)
+
+	return object
+}
+
+// objectSize returns the size of an object, or the bytes transferred when
+// the size wasn't known upfront.
+func (p *TerminalProgress) objectSize(oid string) int64 {
+	size := p.sizes[oid]
+
+	for _, object := range p.running {
+		if object.oid == oid {
+			size = max(size, object.bytes)
+		}
+	}
+
+	return size
+}
+
+func (p *TerminalProgress) finishObject(oid, line string) {
+	p.running = slices.DeleteFunc(p.running, func(object *objectProgress) bool {
+		return object.oid == oid
+	})
+
+	// finished objects are printed above the redrawn block, so they stay
+	p.clear()
+	fmt.Fprintf(p.w, "[%d/%d] %s\n", p.done+p.failed, len(p.sizes), line)
+
+	if p.interactive {
+		p.redraw()
+	}
+}
+
+func (p *TerminalProgress) clear() {
+	if p.drawn > 0 {
+		// move to the first drawn line and erase everything below
+		fmt.Fprintf(p.w, "\x1b[%dF\x1b[J", p.drawn)
+		p.drawn = 0
+	}
+}
+
+func (p *TerminalProgress) redraw() {
+	p.clear()
+
+	var out strings.Builder
+
+	for _, object := range p.running {
+		out.WriteString(objectLine(object))
+		out.WriteByte('\n')
+	}
+
+	out.WriteString(p.footer())
+	out.WriteByte('\n')
+
+	fmt.Fprint(p.w, out.String())
+
+	p.drawn = len(p.running) + 1
+	p.lastDraw = p.now()
+}
+
+func (p *TerminalProgress) footer() string {
+	elapsed := p.now().Sub(p.start).Seconds()
+
+	var rate float64
+	if elapsed > 0 {
+		rate = float64(p.bytes) / elapsed
+	}
+
+	eta := "--"
+	if remaining := p.totalBytes - p.bytes; rate > 0 && remaining > 0 {
+		eta = time.Duration(float64(remaining) / rate * float64(time.Second)).Round(time.Second).String()
+	}
+
+	return fmt.Sprintf(
+		"%d/%d objects  %s / %s  %s/s  ETA %s",
+		p.done+p.failed, len(p.sizes), FormatSize(p.bytes), FormatSize(p.totalBytes), FormatSize(int64(rate)), eta,
+	)
+}
+
+func objectLine(object *objectProgress) string {
+	oid := object.oid[:min(len(object.oid), progressOIDLength)]
+
+	if object.size <= 0 {
+		return fmt.Sprintf("%-*s  %s", progressOIDLength, oid, FormatSize(object.bytes))
+	}
+
+	ratio := min(float64(object.bytes)/float64(object.size), 1)
+	filled := int(ratio * progressBarWidth)
+	bar := strings.Repeat("#", filled) + strings.Repeat("-", progressBarWidth-filled)
+
+	return fmt.Sprintf(
+		"%-*s  [%s] %3.0f%%  %s / %s",
+		progressOIDLength, oid, bar, ratio*100, FormatSize(object.bytes), FormatSize(object.size), //nolint:mnd // percent
+	)
+}
@@ -0,0 +1,87 @@
+package internal
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestProgress(interactive bool, transfers []Transfer) (*TerminalProgress, *bytes.Buffer, *time.Time) {
+	var out bytes.Buffer
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	progress := NewTerminalProgress(&out, interactive, transfers)
+	progress.now = func() time.Time { return now }
+	progress.start, progress.lastDraw = now, now
+
+	return progress, &out, &now
+}
+
+func TestTerminalProgressPlain(t *testing.T) {
+	t.Parallel()
+
+	progress, out, _ := newTestProgress(false, []Transfer{{OID: "aaa", Size: 2048}, {OID: "bbb", Size: 10}})
+
+	progress.Message(ProgressMessage{OID: "aaa", BytesSoFar: 2048, BytesSinceLast: 2048})
+	progress.Message(CompleteMessage{OID: "aaa"})
+	progress.Message(CompleteErrorMessage{OID: "bbb", Error: CompleteErrorMessageContent{Message: "boom"}})
+
+	done, failed := progress.Finish("pushed")
+
+	require.Equal(t, 1, done)
+	require.Equal(t, 1, failed)
+	require.NotContains(t, out.String(), "\x1b[")
+	require.Equal(
+		t,
+		"[1/2] aaa  2.0 KiB\n[2/2] bbb  failed: boom\npushed 1 objects (2.0 KiB) in 0s, 1 failed\n",
+		out.String(),
+	)
+}
+
+func TestTerminalProgressPlainLogsLongTransfers(t *testing.T) {
+	t.Parallel()
+
+	progress, out, now := newTestProgress(false, []Transfer{{OID: "aaa", Size: 100}})
+
+	progress.Message(ProgressMessage{OID: "aaa", BytesSoFar: 10, BytesSinceLast: 10})
+	require.Empty(t, out.String())
+
+	*now = now.Add(progressLogRate)
+	progress.Message(ProgressMessage{OID: "aaa", BytesSoFar: 20, BytesSinceLast: 10})
+	require.Contains(t, out.String(), "0/1 objects")
+}
+
+func TestTerminalProgressInteractive(t *testing.T) {
+	t.Parallel()
+
+	progress, out, now := newTestProgress(true, []Transfer{{OID: "aaa", Size: 1000}, {OID: "bbb"}})
+
+	*now = now.Add(time.Second)
+	progress.Message(ProgressMessage{OID: "aaa", BytesSoFar: 500, BytesSinceLast: 500})
+	progress.Message(ProgressMessage{OID: "bbb", BytesSoFar: 100, BytesSinceLast: 100})
+
+	require.Contains(t, out.String(), "[############------------]  50%")
+	require.Contains(t, out.String(), "0/2 objects  500 B / 1000 B  500 B/s  ETA 1s")
+
+	*now = now.Add(time.Second)
+	progress.Message(CompleteMessage{OID: "aaa"})
+
+	// the drawn block is erased before the finished line is printed
+	require.Contains(t, out.String(), "\x1b[2F\x1b[J[1/2] aaa  1000 B\n")
+
+	progress.Finish("fetched")
+	require.Contains(t, out.String(), "fetched 1 objects (600 B) in 2s, 0 failed\n")
+}
+
+func TestTerminalProgressUnknownSize(t *testing.T) {
+	t.Parallel()
+
+	progress, out, _ := newTestProgress(false, []Transfer{{OID: "aaa"}})
+
+	progress.Message(ProgressMessage{OID: "aaa", BytesSoFar: 300, BytesSinceLast: 300})
+	progress.Message(CompleteMessage{OID: "aaa"})
+
+	require.Contains(t, out.String(), "[1/1] aaa  300 B\n")
+}
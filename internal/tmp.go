@@ -0,0 +1,104 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var ErrTmpFolder = errors.New("failed to resolve temp folder")
+
+const (
+	DefaultTmpMaxAge = 24 * time.Hour
+
+	// uploadStateFolder keeps the state of resumable uploads inside the temp
+	// folder.
+	uploadStateFolder = "uploads"
+)
+
+// DefaultTmpFolder returns the temp folder of git-lfs in the current
+// repository. Downloads placed there are renamed into .git/lfs/objects on
+// the same filesystem instead of being copied.
+func DefaultTmpFolder(ctx context.Context) (string, error) {
+	gitDir, err := GitCommonDir(ctx)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrTmpFolder, err)
+	}
+
+	return filepath.Join(gitDir, "lfs", "tmp"), nil
+}
+
+// LoadTmpFolder returns the temp folder described by the config.
+func LoadTmpFolder(ctx context.Context, config *Config) (string, error) {
+	if config.TmpFolder != "" {
+		// git-lfs may start the agent from any folder of the repository
+		folder, err := filepath.Abs(config.TmpFolder)
+		if err != nil {
+			return "", fmt.Errorf("%w: %w", ErrTmpFolder, err)
+		}
+
+		return folder, nil
+	}
+
+	return DefaultTmpFolder(ctx)
+}
+
+// UploadStateFolder returns the folder resumable uploads keep their state in.
+func UploadStateFolder(tmpFolder string) string {
+	return filepath.Join(tmpFolder, uploadStateFolder)
+}
+
+// CleanTmpFolder removes partial downloads and upload states older than
+// maxAge, which interrupted sessions leave behind. The folder is shared with
+// git-lfs, so only files named after an OID are touched. It returns the
+// number of removed files.
+func CleanTmpFolder(folder string, maxAge time.Duration) (int, error) {
+	var removed int
+
+	deadline := time.Now().Add(-maxAge)
+	stateFolder := UploadStateFolder(folder)
+
+	err := filepath.WalkDir(folder, func(path string, entry fs.DirEntry, err error) error {
+		switch {
+		case errors.Is(err, fs.ErrNotExist) && path == folder:
+			return fs.SkipAll
+		case err != nil:
+			return err
+		case entry.IsDir():
+			if path != folder && path != stateFolder {
+				return fs.SkipDir
+			}
+
+			return nil
+		case !IsOID(strings.TrimSuffix(entry.Name(), ".json")):
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err //nolint:wrapcheck // wrapped below
+		}
+
+		if info.ModTime().After(deadline) {
+			return nil
+		}
+
+		if err := os.Remove(path); err != nil {
+			return err //nolint:wrapcheck // wrapped below
+		}
+
+		removed++
+
+		return nil
+	})
+	if err != nil {
+		return removed, fmt.Errorf("failed to clean temp folder: %w", err)
+	}
+
+	return removed, nil
+}
@@ -0,0 +1,86 @@
+package internal
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCleanTmpFolder(t *testing.T) {
+	t.Parallel()
+
+	folder := t.TempDir()
+	stateFolder := UploadStateFolder(folder)
+	require.NoError(t, os.MkdirAll(stateFolder, 0o700))
+
+	old := time.Now().Add(-2 * time.Hour)
+	files := map[string]bool{
+		filepath.Join(folder, testPointerA):                    true,
+		filepath.Join(stateFolder, testPointerA+".json"):       true,
+		filepath.Join(folder, testPointerB):                    false,
+		filepath.Join(folder, "git-lfs-owned.tmp"):             false,
+		filepath.Join(folder, "nested", testPointerA):          false,
+		filepath.Join(stateFolder, "nested", testPointerA):     false,
+		filepath.Join(folder, "incomplete"+testPointerA[:10]):  false,
+		filepath.Join(stateFolder, testPointerB+".json.extra"): false,
+	}
+
+	for path := range files {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o700))
+		require.NoError(t, os.WriteFile(path, []byte("partial"), 0o600))
+
+		if filepath.Base(path) != testPointerB {
+			require.NoError(t, os.Chtimes(path, old, old))
+		}
+	}
+
+	removed, err := CleanTmpFolder(folder, time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, 2, removed)
+
+	for path, gone := range files {
+		_, err := os.Stat(path)
+		require.Equal(t, gone, os.IsNotExist(err), path)
+	}
+}
+
+func TestCleanTmpFolderMissing(t *testing.T) {
+	t.Parallel()
+
+	removed, err := CleanTmpFolder(filepath.Join(t.TempDir(), "missing"), time.Hour)
+	require.NoError(t, err)
+	require.Zero(t, removed)
+}
+
+//nolint:paralleltest // changes the working directory
+func TestDefaultTmpFolder(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	repository, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+
+	out, err := exec.Command("git", "init", "-q", repository).CombinedOutput()
+	require.NoError(t, err, string(out))
+
+	subfolder := filepath.Join(repository, "sub")
+	require.NoError(t, os.Mkdir(subfolder, 0o700))
+	t.Chdir(subfolder)
+
+	gitDir, err := GitCommonDir(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(repository, ".git"), gitDir)
+
+	folder, err := DefaultTmpFolder(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(repository, ".git", "lfs", "tmp"), folder)
+
+	folder, err = LoadTmpFolder(t.Context(), &Config{TmpFolder: "custom"})
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(subfolder, "custom"), folder)
+}
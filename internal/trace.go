@@ -0,0 +1,154 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+var ErrReadTrace = errors.New("failed to read trace")
+
+type TraceDirection string
+
+const (
+	TraceDirectionIn  TraceDirection = "in"
+	TraceDirectionOut TraceDirection = "out"
+)
+
+// TraceRecord is a single protocol line as stored in a trace file.
+type TraceRecord struct {
+	Time      time.Time      `json:"time"`
+	Direction TraceDirection `json:"direction"`
+	Line      string         `json:"line"`
+}
+
+// Tracer records every protocol line exchanged with git-lfs as JSON lines.
+type Tracer struct {
+	mu     sync.Mutex
+	writer io.Writer
+}
+
+func NewTracer(writer io.Writer) *Tracer {
+	return &Tracer{writer: writer}
+}
+
+func (t *Tracer) record(direction TraceDirection, line []byte) {
+	data, err := json.Marshal(TraceRecord{time.Now(), direction, string(line)})
+	if err != nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// tracing is a debugging aid and must never break the transfer
+	_, _ = t.writer.Write(append(data, '\n'))
+}
+
+// Reader returns a reader recording every line read from r as inbound.
+func (t *Tracer) Reader(r io.Reader) io.Reader {
+	return &traceReader{reader: r, lines: traceLines{tracer: t, direction: TraceDirectionIn}}
+}
+
+// Writer returns a writer recording every line written to w as outbound.
+func (t *Tracer) Writer(w io.Writer) io.Writer {
+	return &traceWriter{writer: w, lines: traceLines{tracer: t, direction: TraceDirectionOut}}
+}
+
+// traceLines splits a byte stream into lines, since protocol messages may
+// be read or written in several pieces.
+type traceLines struct {
+	tracer    *Tracer
+	direction TraceDirection
+	pending   []byte
+}
+
+func (l *traceLines) feed(p []byte) {
+	l.pending = append(l.pending, p...)
+
+	for {
+		idx := bytes.IndexByte(l.pending, '\n')
+		if idx < 0 {
+			return
+		}
+
+		l.tracer.record(l.direction, l.pending[:idx])
+		l.pending = l.pending[idx+1:]
+	}
+}
+
+func (l *traceLines) flush() {
+	if len(l.pending) > 0 {
+		l.tracer.record(l.direction, l.pending)
+		l.pending = nil
+	}
+}
+
+type traceReader struct {
+	reader io.Reader
+	lines  traceLines
+}
+
+func (tr *traceReader) Read(p []byte) (int, error) {
+	n, err := tr.reader.Read(p)
+	tr.lines.feed(p[:n])
+
+	if err != nil {
+		tr.lines.flush()
+	}
+
+	return n, err //nolint:wrapcheck // reader is transparent
+}
+
+type traceWriter struct {
+	mu     sync.Mutex
+	writer io.Writer
+	lines  traceLines
+}
+
+func (tw *traceWriter) Write(p []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	n, err := tw.writer.Write(p)
+	tw.lines.feed(p[:n])
+
+	return n, err //nolint:wrapcheck // writer is transparent
+}
+
+// ReadTrace reads the records of a trace file.
+func ReadTrace(r io.Reader) ([]TraceRecord, error) {
+	var records []TraceRecord
+
+	decoder := json.NewDecoder(r)
+
+	for {
+		var record TraceRecord
+
+		err := decoder.Decode(&record)
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("%w: record %d: %w", ErrReadTrace, len(records)+1, err)
+		}
+
+		records = append(records, record)
+	}
+}
+
+// ReplayInput joins the inbound lines of a trace back into a protocol stream.
+func ReplayInput(records []TraceRecord) io.Reader {
+	var input bytes.Buffer
+
+	for _, record := range records {
+		if record.Direction == TraceDirectionIn {
+			input.WriteString(record.Line + "\n")
+		}
+	}
+
+	return &input
+}
@@ -0,0 +1,48 @@
+package internal
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTracerRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	var trace, stdout bytes.Buffer
+
+	tracer := NewTracer(&trace)
+
+	// lines arrive in pieces
+	input := "{ \"event\": \"init\" }\n{ \"event\": \"terminate\" }\n"
+	read, err := io.ReadAll(tracer.Reader(iotest.OneByteReader(strings.NewReader(input))))
+	require.NoError(t, err)
+	require.Equal(t, input, string(read))
+
+	// dial writes a message and its newline separately
+	writer := tracer.Writer(&stdout)
+	_, err = writer.Write([]byte("{ }"))
+	require.NoError(t, err)
+	_, err = writer.Write([]byte("\n"))
+	require.NoError(t, err)
+	require.Equal(t, "{ }\n", stdout.String())
+
+	records, err := ReadTrace(&trace)
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	require.Equal(t, TraceDirectionIn, records[0].Direction)
+	require.Equal(t, `{ "event": "init" }`, records[0].Line)
+	require.Equal(t, TraceDirectionOut, records[2].Direction)
+	require.Equal(t, "{ }", records[2].Line)
+
+	replayed, err := io.ReadAll(ReplayInput(records))
+	require.NoError(t, err)
+	require.Equal(t, input, string(replayed))
+
+	_, err = ReadTrace(strings.NewReader("not a trace"))
+	require.ErrorIs(t, err, ErrReadTrace)
+}
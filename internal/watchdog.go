@@ -0,0 +1,146 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var (
+	ErrTransferStalled  = errors.New("transfer stalled")
+	ErrTransferDeadline = errors.New("transfer deadline exceeded")
+)
+
+// stallWatchdog cancels a transfer when it makes no progress for the idle
+// timeout, e.g. when an HTTP body stops delivering bytes.
+type stallWatchdog struct {
+	mu    sync.Mutex
+	timer *time.Timer
+	idle  time.Duration
+}
+
+type watchdogContextKey struct{}
+
+// newTransferContext derives a transfer context from the session one. It's
+// cancelled with ErrTransferStalled once touch isn't called for idle and with
+// ErrTransferDeadline after deadline. Zero durations disable the limits.
+func newTransferContext(
+	ctx context.Context,
+	idle, deadline time.Duration,
+) (context.Context, *stallWatchdog, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	watchdog := &stallWatchdog{idle: idle}
+	ctx = context.WithValue(ctx, watchdogContextKey{}, watchdog)
+
+	if idle > 0 {
+		watchdog.timer = time.AfterFunc(idle, func() {
+			cancel(ErrTransferStalled)
+		})
+	}
+
+	stop := func() {
+		watchdog.stop()
+		cancel(context.Canceled)
+	}
+
+	if deadline <= 0 {
+		return ctx, watchdog, stop
+	}
+
+	ctx, cancelDeadline := context.WithTimeoutCause(ctx, deadline, ErrTransferDeadline)
+
+	return ctx, watchdog, func() {
+		cancelDeadline()
+		stop()
+	}
+}
+
+// touch postpones the stall timeout, it's called on every transferred chunk.
+func (w *stallWatchdog) touch() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.timer != nil {
+		w.timer.Reset(w.idle)
+	}
+}
+
+// pause stops the stall timeout until the next touch, e.g. while the server
+// processes a request body which is already sent.
+func (w *stallWatchdog) pause() {
+	w.stop()
+}
+
+func (w *stallWatchdog) stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+}
+
+// transferError attributes the error of a cancelled transfer to its cause.
+func transferError(ctx context.Context, err error) error {
+	cause := context.Cause(ctx)
+
+	if errors.Is(cause, ErrTransferStalled) || errors.Is(cause, ErrTransferDeadline) {
+		return errors.Join(cause, err)
+	}
+
+	return err
+}
+
+// WatchdogTransport keeps the stall watchdog of a transfer in sync with its
+// HTTP requests. Every request body write postpones the stall timeout, which
+// is paused while the response headers of a sent body are awaited, so large
+// or buffered uploads aren't mistaken for stalled ones.
+type WatchdogTransport struct {
+	base http.RoundTripper
+}
+
+func NewWatchdogTransport(base http.RoundTripper) *WatchdogTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return &WatchdogTransport{base: base}
+}
+
+func (t *WatchdogTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	watchdog, ok := req.Context().Value(watchdogContextKey{}).(*stallWatchdog)
+	if !ok || req.Body == nil || req.Body == http.NoBody {
+		return t.base.RoundTrip(req) //nolint:wrapcheck // transparent transport
+	}
+
+	req = req.Clone(req.Context())
+	req.Body = &watchdogBody{ReadCloser: req.Body, watchdog: watchdog}
+
+	resp, err := t.base.RoundTrip(req)
+	watchdog.touch()
+
+	return resp, err //nolint:wrapcheck // transparent transport
+}
+
+// watchdogBody touches the watchdog as the transport writes the request body
+// and pauses it once the body is sent.
+type watchdogBody struct {
+	io.ReadCloser
+
+	watchdog *stallWatchdog
+}
+
+func (b *watchdogBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+
+	if errors.Is(err, io.EOF) {
+		b.watchdog.pause()
+	} else if n > 0 {
+		b.watchdog.touch()
+	}
+
+	return n, err //nolint:wrapcheck // transparent reader
+}
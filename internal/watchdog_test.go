@@ -0,0 +1,133 @@
+package internal
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// stallingRepository serves downloads which never deliver a byte.
+type stallingRepository struct{}
+
+func (stallingRepository) Upload(context.Context, string, io.Reader, bool) error {
+	return nil
+}
+
+func (stallingRepository) Download(ctx context.Context, _ string) (io.ReadCloser, error) {
+	return io.NopCloser(stallingReader{ctx}), nil
+}
+
+type stallingReader struct {
+	ctx context.Context //nolint:containedctx // mimics an HTTP body bound to the request context
+}
+
+func (r stallingReader) Read(_ []byte) (int, error) {
+	<-r.ctx.Done()
+
+	return 0, r.ctx.Err()
+}
+
+func TestStalledTransfer(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		idle     time.Duration
+		deadline time.Duration
+		code     int64
+	}{
+		{"Idle", 50 * time.Millisecond, 0, ErrorCodeStalled},
+		{"Deadline", 0, 50 * time.Millisecond, ErrorCodeDeadline},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			messages := make(chan DialMessage)
+			controller := NewController(stallingRepository{}, t.TempDir(), messages, WithTransferTimeouts(tt.idle, tt.deadline))
+
+			input := strings.Join([]string{
+				`{ "event": "init", "operation": "download", "remote": "origin", "concurrent": true, "concurrenttransfers": 1 }`,
+				`{ "event": "download", "oid": "stalled", "size": 5, "path": "" }`,
+				`{ "event": "terminate" }`,
+			}, "\n")
+
+			outR, outW := io.Pipe()
+
+			ctx, cancel := context.WithCancel(t.Context())
+			defer cancel()
+
+			go NewDial(outW, messages).ListenAndServe(ctx)
+			go func() {
+				_ = NewDispatcher(strings.NewReader(input), controller).ListenAndServe(ctx)
+			}()
+
+			scanner := bufio.NewScanner(outR)
+			for scanner.Scan() {
+				if strings.Contains(scanner.Text(), `"complete"`) {
+					require.Contains(t, scanner.Text(), fmt.Sprintf(`"code":%d`, tt.code))
+
+					return
+				}
+			}
+
+			t.Fatal("no complete message")
+		})
+	}
+}
+
+func TestStallWatchdogTouch(t *testing.T) {
+	t.Parallel()
+
+	ctx, watchdog, cancel := newTransferContext(t.Context(), 30*time.Millisecond, 0)
+	defer cancel()
+
+	for range 5 {
+		time.Sleep(10 * time.Millisecond)
+		watchdog.touch()
+	}
+
+	require.NoError(t, ctx.Err(), "progressing transfer must not be cancelled")
+
+	<-ctx.Done()
+	require.ErrorIs(t, context.Cause(ctx), ErrTransferStalled)
+}
+
+func TestWatchdogTransport(t *testing.T) {
+	t.Parallel()
+
+	// the server answers long after the idle timeout once the body is sent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+
+		time.Sleep(100 * time.Millisecond)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	t.Cleanup(server.Close)
+
+	ctx, _, cancel := newTransferContext(t.Context(), 30*time.Millisecond, 0)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, server.URL, strings.NewReader(strings.Repeat("x", 1<<20)))
+	require.NoError(t, err)
+
+	client := &http.Client{Transport: NewWatchdogTransport(nil)}
+
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	// the timer runs again once the response arrives
+	<-ctx.Done()
+	require.ErrorIs(t, context.Cause(ctx), ErrTransferStalled)
+}
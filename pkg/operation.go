@@ -0,0 +1,129 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+var (
+	ErrOperationFailed        = errors.New("asynchronous operation failed")
+	ErrOperationStatus        = errors.New("failed to get operation status")
+	ErrDecodeOperation        = errors.New("failed to decode operation response")
+	ErrUnknownOperationStatus = errors.New("unknown operation status")
+)
+
+// OperationStatus is the state of an asynchronous Yandex Disk operation.
+type OperationStatus string
+
+const (
+	OperationStatusSuccess    OperationStatus = "success"
+	OperationStatusFailed     OperationStatus = "failed"
+	OperationStatusInProgress OperationStatus = "in-progress"
+)
+
+// OperationBackoff configures how often asynchronous operations are polled.
+// The interval starts at Initial and grows by Multiplier up to Max.
+type OperationBackoff struct {
+	Initial    time.Duration
+	Max        time.Duration
+	Multiplier float64
+}
+
+//nolint:gochecknoglobals,mnd // default settings, copied into every client
+var DefaultOperationBackoff = OperationBackoff{
+	Initial:    200 * time.Millisecond,
+	Max:        5 * time.Second,
+	Multiplier: 1.5,
+}
+
+func (b OperationBackoff) next(interval time.Duration) time.Duration {
+	next := time.Duration(float64(interval) * max(b.Multiplier, 1))
+
+	if b.Max > 0 && next > b.Max {
+		return b.Max
+	}
+
+	return next
+}
+
+type yandexDiskOperationResponse struct {
+	Status OperationStatus `json:"status"`
+}
+
+// WaitOperation polls the operation at href until it succeeds, fails or ctx
+// is done. Endpoints answering 202 Accepted return such an href.
+func (c *YandexDiskClient) WaitOperation(ctx context.Context, href string) error {
+	interval := max(c.Operations.Initial, time.Millisecond)
+
+	for {
+		status, err := c.OperationStatus(ctx, href)
+		if err != nil {
+			return err
+		}
+
+		switch status {
+		case OperationStatusSuccess:
+			return nil
+		case OperationStatusFailed:
+			return ErrOperationFailed
+		case OperationStatusInProgress:
+		default:
+			return fmt.Errorf("%w: %s", ErrUnknownOperationStatus, status)
+		}
+
+		timer := time.NewTimer(interval)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+
+			return fmt.Errorf("%w: %w", ErrOperationFailed, ctx.Err())
+		case <-timer.C:
+		}
+
+		interval = c.Operations.next(interval)
+	}
+}
+
+// OperationStatus returns the current status of the operation at href.
+func (c *YandexDiskClient) OperationStatus(ctx context.Context, href string) (OperationStatus, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, href, nil)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrCreateRequest, err)
+	}
+
+	req.Header.Set("Authorization", "OAuth "+c.OAuthToken)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrOperationStatus, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%w: %s", ErrOperationStatus, resp.Status)
+	}
+
+	operation := yandexDiskOperationResponse{}
+
+	if err := json.NewDecoder(resp.Body).Decode(&operation); err != nil {
+		return "", fmt.Errorf("%w: %w", ErrDecodeOperation, err)
+	}
+
+	return operation.Status, nil
+}
+
+// waitAccepted waits for the operation linked from a 202 Accepted response.
+func (c *YandexDiskClient) waitAccepted(ctx context.Context, resp *http.Response) error {
+	link := yandexDiskClientResponse{}
+
+	if err := json.NewDecoder(resp.Body).Decode(&link); err != nil {
+		return fmt.Errorf("%w: %w", ErrDecodeOperation, err)
+	}
+
+	return c.WaitOperation(ctx, link.Href)
+}
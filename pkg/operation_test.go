@@ -0,0 +1,62 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestYandexDiskClientWaitOperation(t *testing.T) {
+	t.Parallel()
+
+	var polls atomic.Int64
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := OperationStatusInProgress
+
+		switch r.URL.Path {
+		case "/operations/eventually":
+			if polls.Add(1) == 3 {
+				status = OperationStatusSuccess
+			}
+		case "/operations/failed":
+			status = OperationStatusFailed
+		case "/operations/weird":
+			status = "cancelled"
+		}
+
+		require.NoError(t, json.NewEncoder(w).Encode(yandexDiskOperationResponse{Status: status}))
+	}))
+	defer server.Close()
+
+	client := NewYandexDiskClient("token", "/project")
+	client.Operations = OperationBackoff{Initial: time.Millisecond, Max: 5 * time.Millisecond, Multiplier: 2}
+
+	require.NoError(t, client.WaitOperation(t.Context(), server.URL+"/operations/eventually"))
+	require.Equal(t, int64(3), polls.Load())
+
+	require.ErrorIs(t, client.WaitOperation(t.Context(), server.URL+"/operations/failed"), ErrOperationFailed)
+	require.ErrorIs(t, client.WaitOperation(t.Context(), server.URL+"/operations/weird"), ErrUnknownOperationStatus)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 20*time.Millisecond)
+	defer cancel()
+
+	err := client.WaitOperation(ctx, server.URL+"/operations/forever")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestOperationBackoff(t *testing.T) {
+	t.Parallel()
+
+	backoff := OperationBackoff{Initial: time.Second, Max: 3 * time.Second, Multiplier: 2}
+
+	require.Equal(t, 2*time.Second, backoff.next(time.Second))
+	require.Equal(t, 3*time.Second, backoff.next(2*time.Second))
+	require.Equal(t, time.Second, OperationBackoff{Multiplier: 0.5}.next(time.Second))
+}
@@ -0,0 +1,503 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrS3Multipart      = errors.New("failed to upload multipart object")
+	ErrDecodeS3Response = errors.New("failed to decode S3 response")
+)
+
+const (
+	// DefaultS3PartSize is the part size of multipart uploads, S3 requires
+	// at least 5 MiB for every part but the last.
+	DefaultS3PartSize = 8 << 20
+
+	s3Algorithm     = "AWS4-HMAC-SHA256"
+	s3TimeFormat    = "20060102T150405Z"
+	s3ListPageSize  = 1000
+	s3DefaultRegion = "us-east-1"
+)
+
+// S3Client stores objects in a bucket of an S3 compatible storage, under
+// Prefix. Requests use path-style URLs, which AWS and the self-hosted
+// servers accept alike.
+type S3Client struct {
+	// Endpoint defaults to the AWS endpoint of the region.
+	Endpoint        string
+	Region          string
+	Bucket          string
+	Prefix          string
+	AccessKeyID     string
+	SecretAccessKey string
+	PartSize        int64
+	HTTPClient      *http.Client
+	UserAgent       string
+
+	now func() time.Time
+}
+
+// NewS3Client creates a new S3Client for the bucket.
+func NewS3Client(bucket, prefix, accessKeyID, secretAccessKey string) *S3Client {
+	return &S3Client{
+		Region:          s3DefaultRegion,
+		Bucket:          bucket,
+		Prefix:          strings.Trim(prefix, "/"),
+		AccessKeyID:     accessKeyID,
+		SecretAccessKey: secretAccessKey,
+		PartSize:        DefaultS3PartSize,
+		HTTPClient:      http.DefaultClient,
+		UserAgent:       "yadlfs",
+		now:             time.Now,
+	}
+}
+
+type s3ErrorResponse struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+type s3InitiateMultipartResponse struct {
+	UploadID string `xml:"UploadId"`
+}
+
+type s3CompletedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+type s3CompleteMultipartRequest struct {
+	XMLName xml.Name          `xml:"CompleteMultipartUpload"`
+	Parts   []s3CompletedPart `xml:"Part"`
+}
+
+type s3ListResponse struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// ObjectPath returns the URL of an object, e.g. for reports.
+func (c *S3Client) ObjectPath(name string) string {
+	return "s3://" + c.Bucket + "/" + c.objectKey(name)
+}
+
+func (c *S3Client) objectKey(name string) string {
+	return strings.TrimPrefix(path.Join(c.Prefix, name), "/")
+}
+
+func (c *S3Client) endpoint() string {
+	if c.Endpoint != "" {
+		return strings.TrimSuffix(c.Endpoint, "/")
+	}
+
+	return "https://s3." + c.Region + ".amazonaws.com"
+}
+
+// Upload uploads an object. Objects which fit into a part are sent with a
+// single PUT, bigger ones with a multipart upload, so the stream is never
+// buffered beyond a part. S3 always replaces existing objects.
+func (c *S3Client) Upload(ctx context.Context, filePath string, r io.Reader, _ bool) error {
+	part := make([]byte, max(c.PartSize, 1))
+
+	n, err := io.ReadFull(r, part)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return c.putObject(ctx, c.objectKey(filePath), part[:n])
+	} else if err != nil {
+		return fmt.Errorf("%w: %w", ErrUploadFile, err)
+	}
+
+	return c.uploadMultipart(ctx, c.objectKey(filePath), r, part)
+}
+
+func (c *S3Client) putObject(ctx context.Context, key string, data []byte) error {
+	resp, err := c.request(ctx, http.MethodPut, key, nil, data)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrUploadFile, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: %s", ErrUploadFile, s3Status(resp))
+	}
+
+	return nil
+}
+
+// uploadMultipart uploads the first, already read, part and the rest of the
+// stream part by part. A failed upload is aborted, so its parts aren't kept.
+func (c *S3Client) uploadMultipart(ctx context.Context, key string, r io.Reader, part []byte) error {
+	uploadID, err := c.initiateMultipart(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	var parts []s3CompletedPart
+
+	for number := 1; ; number++ {
+		etag, err := c.uploadPart(ctx, key, uploadID, number, part)
+		if err != nil {
+			return c.abortMultipart(key, uploadID, err)
+		}
+
+		parts = append(parts, s3CompletedPart{PartNumber: number, ETag: etag})
+
+		n, err := io.ReadFull(r, part)
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return c.abortMultipart(key, uploadID, err)
+		}
+
+		part = part[:n]
+	}
+
+	if err := c.completeMultipart(ctx, key, uploadID, parts); err != nil {
+		return c.abortMultipart(key, uploadID, err)
+	}
+
+	return nil
+}
+
+func (c *S3Client) initiateMultipart(ctx context.Context, key string) (string, error) {
+	resp, err := c.request(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, nil)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrS3Multipart, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%w: %s", ErrS3Multipart, s3Status(resp))
+	}
+
+	var initiated s3InitiateMultipartResponse
+
+	if err := xml.NewDecoder(resp.Body).Decode(&initiated); err != nil {
+		return "", fmt.Errorf("%w: %w", ErrDecodeS3Response, err)
+	}
+
+	return initiated.UploadID, nil
+}
+
+func (c *S3Client) uploadPart(ctx context.Context, key, uploadID string, number int, data []byte) (string, error) {
+	query := url.Values{"partNumber": {strconv.Itoa(number)}, "uploadId": {uploadID}}
+
+	resp, err := c.request(ctx, http.MethodPut, key, query, data)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrS3Multipart, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%w: part %d: %s", ErrS3Multipart, number, s3Status(resp))
+	}
+
+	return resp.Header.Get("ETag"), nil
+}
+
+func (c *S3Client) completeMultipart(ctx context.Context, key, uploadID string, parts []s3CompletedPart) error {
+	body, err := xml.Marshal(s3CompleteMultipartRequest{Parts: parts})
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrS3Multipart, err)
+	}
+
+	resp, err := c.request(ctx, http.MethodPost, key, url.Values{"uploadId": {uploadID}}, body)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrS3Multipart, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrS3Multipart, err)
+	}
+
+	// the completion may fail after the 200 status has been sent
+	var failure s3ErrorResponse
+	if resp.StatusCode != http.StatusOK || xml.Unmarshal(data, &failure) == nil && failure.Code != "" {
+		return fmt.Errorf("%w: %s %s: %s", ErrS3Multipart, resp.Status, failure.Code, failure.Message)
+	}
+
+	return nil
+}
+
+// abortMultipart drops the uploaded parts and returns cause. It runs even
+// when the upload was canceled, the parts would be billed otherwise.
+func (c *S3Client) abortMultipart(key, uploadID string, cause error) error {
+	resp, err := c.request(context.Background(), http.MethodDelete, key, url.Values{"uploadId": {uploadID}}, nil)
+	if err != nil {
+		return errors.Join(cause, fmt.Errorf("failed to abort multipart upload: %w", err))
+	}
+	defer resp.Body.Close()
+
+	return cause
+}
+
+// Download downloads an object.
+func (c *S3Client) Download(ctx context.Context, filePath string) (io.ReadCloser, error) {
+	resp, err := c.request(ctx, http.MethodGet, c.objectKey(filePath), nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDownloadFile, err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+
+		return nil, fmt.Errorf("%w: %w: %s", ErrDownloadFile, ErrResourceNotFound, filePath)
+	default:
+		defer resp.Body.Close()
+
+		return nil, fmt.Errorf("%w: %s", ErrDownloadFile, s3Status(resp))
+	}
+}
+
+// List lists the objects stored directly under the prefix, following the
+// pagination.
+func (c *S3Client) List(ctx context.Context) ([]Resource, error) {
+	var resources []Resource
+
+	prefix := ""
+	if c.Prefix != "" {
+		prefix = c.Prefix + "/"
+	}
+
+	query := url.Values{
+		"list-type": {"2"},
+		"prefix":    {prefix},
+		"delimiter": {"/"},
+		"max-keys":  {strconv.Itoa(s3ListPageSize)},
+	}
+
+	for {
+		page, err := c.listPage(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, object := range page.Contents {
+			resources = append(resources, Resource{
+				Name:     path.Base(object.Key),
+				Path:     object.Key,
+				Type:     "file",
+				Size:     object.Size,
+				Modified: object.LastModified,
+			})
+		}
+
+		if !page.IsTruncated {
+			return resources, nil
+		}
+
+		query.Set("continuation-token", page.NextContinuationToken)
+	}
+}
+
+func (c *S3Client) listPage(ctx context.Context, query url.Values) (*s3ListResponse, error) {
+	resp, err := c.request(ctx, http.MethodGet, "", query, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrListResources, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %s", ErrListResources, s3Status(resp))
+	}
+
+	page := &s3ListResponse{}
+
+	if err := xml.NewDecoder(resp.Body).Decode(page); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDecodeListResponse, err)
+	}
+
+	return page, nil
+}
+
+// Delete deletes an object. S3 has no trash, so objects are always deleted
+// permanently, and deleting a missing object succeeds.
+func (c *S3Client) Delete(ctx context.Context, filePath string, _ bool) error {
+	resp, err := c.request(ctx, http.MethodDelete, c.objectKey(filePath), nil, nil)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrDeleteResource, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: %s", ErrDeleteResource, s3Status(resp))
+	}
+
+	return nil
+}
+
+// request sends a signed request for an object key, or for the bucket when
+// the key is empty.
+func (c *S3Client) request(
+	ctx context.Context,
+	method, key string,
+	query url.Values,
+	body []byte,
+) (*http.Response, error) {
+	target := c.endpoint() + "/" + s3Escape(c.Bucket, false)
+	if key != "" {
+		target += "/" + s3Escape(key, true)
+	}
+
+	if len(query) > 0 {
+		target += "?" + s3CanonicalQuery(query)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrCreateRequest, err)
+	}
+
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+
+	sum := sha256.Sum256(body)
+	c.sign(req, hex.EncodeToString(sum[:]))
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	return httpClient.Do(req) //nolint:wrapcheck // wrapped by the callers
+}
+
+// sign adds the AWS Signature Version 4 of the request. Host, the x-amz-*
+// headers and the content headers are signed.
+func (c *S3Client) sign(req *http.Request, payloadHash string) {
+	clock := c.now
+	if clock == nil {
+		clock = time.Now
+	}
+
+	timestamp := clock().UTC().Format(s3TimeFormat)
+	scope := timestamp[:8] + "/" + c.Region + "/s3/aws4_request"
+
+	req.Header.Set("X-Amz-Date", timestamp)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	names := []string{"host"}
+
+	for name := range req.Header {
+		name = strings.ToLower(name)
+		if strings.HasPrefix(name, "x-amz-") || name == "content-type" || name == "content-md5" || name == "range" {
+			names = append(names, name)
+		}
+	}
+
+	slices.Sort(names)
+
+	var headers strings.Builder
+
+	for _, name := range names {
+		value := req.Host
+		if value == "" {
+			value = req.URL.Host
+		}
+
+		if name != "host" {
+			value = strings.TrimSpace(req.Header.Get(name))
+		}
+
+		headers.WriteString(name + ":" + value + "\n")
+	}
+
+	signedHeaders := strings.Join(names, ";")
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		s3CanonicalQuery(req.URL.Query()),
+		headers.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{s3Algorithm, timestamp, scope, hex.EncodeToString(requestHash[:])}, "\n")
+
+	key := []byte("AWS4" + c.SecretAccessKey)
+	for _, part := range []string{timestamp[:8], c.Region, "s3", "aws4_request"} {
+		key = s3HMAC(key, part)
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, c.AccessKeyID, scope, signedHeaders, hex.EncodeToString(s3HMAC(key, stringToSign)),
+	))
+}
+
+func s3HMAC(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+
+	return mac.Sum(nil)
+}
+
+// s3Escape encodes everything but the unreserved characters, as the
+// signature requires, keeping the slashes of object keys.
+func s3Escape(s string, keepSlash bool) string {
+	var escaped strings.Builder
+
+	for _, b := range []byte(s) {
+		switch {
+		case 'A' <= b && b <= 'Z', 'a' <= b && b <= 'z', '0' <= b && b <= '9',
+			b == '-', b == '_', b == '.', b == '~', keepSlash && b == '/':
+			escaped.WriteByte(b)
+		default:
+			fmt.Fprintf(&escaped, "%%%02X", b)
+		}
+	}
+
+	return escaped.String()
+}
+
+func s3CanonicalQuery(query url.Values) string {
+	pairs := make([]string, 0, len(query))
+
+	for key, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, s3Escape(key, false)+"="+s3Escape(value, false))
+		}
+	}
+
+	slices.Sort(pairs)
+
+	return strings.Join(pairs, "&")
+}
+
+// s3Status describes a failed response with the S3 error code, e.g.
+// "403 Forbidden: SignatureDoesNotMatch".
+func s3Status(resp *http.Response) string {
+	var failure s3ErrorResponse
+	if err := xml.NewDecoder(resp.Body).Decode(&failure); err != nil || failure.Code == "" {
+		return resp.Status
+	}
+
+	return resp.Status + ": " + failure.Code
+}
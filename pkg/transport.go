@@ -0,0 +1,112 @@
+package pkg
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+var (
+	ErrProxyURL = errors.New("invalid proxy URL")
+	ErrCABundle = errors.New("failed to load CA bundle")
+)
+
+const (
+	defaultDialTimeout     = 30 * time.Second
+	defaultIdleConnTimeout = 90 * time.Second
+)
+
+// TransportConfig describes the HTTP transport used to talk to Yandex Disk.
+// Zero values keep the net/http defaults.
+type TransportConfig struct {
+	// Proxy is an http://, https:// or socks5:// URL. When empty the
+	// HTTP_PROXY/HTTPS_PROXY/NO_PROXY variables are honoured.
+	Proxy string
+	// CABundle is a PEM file with certificates trusted in addition to the
+	// system ones, e.g. the certificate of an intercepting corporate proxy.
+	CABundle              string
+	MaxIdleConns          int
+	MaxConnsPerHost       int
+	TLSHandshakeTimeout   time.Duration
+	ResponseHeaderTimeout time.Duration
+}
+
+// NewHTTPClient builds an *http.Client for YandexDiskClient.HTTPClient.
+// There is no overall client timeout, since object transfers may take hours.
+func NewHTTPClient(config TransportConfig) (*http.Client, error) {
+	transport, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		transport = &http.Transport{}
+	}
+
+	transport = transport.Clone()
+	transport.DialContext = (&net.Dialer{Timeout: defaultDialTimeout, KeepAlive: defaultDialTimeout}).DialContext
+	transport.IdleConnTimeout = defaultIdleConnTimeout
+
+	if config.Proxy != "" {
+		proxyURL, err := url.Parse(config.Proxy)
+		if err != nil || proxyURL.Host == "" {
+			return nil, fmt.Errorf("%w: %s", ErrProxyURL, config.Proxy)
+		}
+
+		switch proxyURL.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, fmt.Errorf("%w: unsupported scheme %s", ErrProxyURL, proxyURL.Scheme)
+		}
+
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if config.CABundle != "" {
+		pool, err := loadCABundle(config.CABundle)
+		if err != nil {
+			return nil, err
+		}
+
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+
+	if config.MaxIdleConns > 0 {
+		transport.MaxIdleConns = config.MaxIdleConns
+		transport.MaxIdleConnsPerHost = config.MaxIdleConns
+	}
+
+	if config.MaxConnsPerHost > 0 {
+		transport.MaxConnsPerHost = config.MaxConnsPerHost
+	}
+
+	if config.TLSHandshakeTimeout > 0 {
+		transport.TLSHandshakeTimeout = config.TLSHandshakeTimeout
+	}
+
+	if config.ResponseHeaderTimeout > 0 {
+		transport.ResponseHeaderTimeout = config.ResponseHeaderTimeout
+	}
+
+	return &http.Client{Transport: transport}, nil
+}
+
+func loadCABundle(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrCABundle, err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("%w: no certificates found in %s", ErrCABundle, path)
+	}
+
+	return pool, nil
+}
@@ -0,0 +1,71 @@
+package pkg
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewHTTPClient(t *testing.T) {
+	t.Parallel()
+
+	client, err := NewHTTPClient(TransportConfig{
+		Proxy:                 "socks5://127.0.0.1:1080",
+		MaxIdleConns:          4,
+		MaxConnsPerHost:       2,
+		ResponseHeaderTimeout: time.Second,
+	})
+	require.NoError(t, err)
+
+	transport, ok := client.Transport.(*http.Transport)
+	require.True(t, ok)
+	require.Equal(t, 4, transport.MaxIdleConns)
+	require.Equal(t, 2, transport.MaxConnsPerHost)
+	require.Equal(t, time.Second, transport.ResponseHeaderTimeout)
+
+	proxy, err := transport.Proxy(httptest.NewRequest(http.MethodGet, "https://cloud-api.yandex.net", nil))
+	require.NoError(t, err)
+	require.Equal(t, "socks5://127.0.0.1:1080", proxy.String())
+}
+
+func TestNewHTTPClientErrors(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewHTTPClient(TransportConfig{Proxy: "ftp://proxy:21"})
+	require.ErrorIs(t, err, ErrProxyURL)
+
+	_, err = NewHTTPClient(TransportConfig{Proxy: "proxy"})
+	require.ErrorIs(t, err, ErrProxyURL)
+
+	_, err = NewHTTPClient(TransportConfig{CABundle: filepath.Join(t.TempDir(), "missing.pem")})
+	require.ErrorIs(t, err, ErrCABundle)
+
+	bundle := filepath.Join(t.TempDir(), "bundle.pem")
+	require.NoError(t, os.WriteFile(bundle, []byte("not a certificate"), 0o600))
+
+	_, err = NewHTTPClient(TransportConfig{CABundle: bundle})
+	require.ErrorIs(t, err, ErrCABundle)
+}
+
+func TestYandexDiskClientUserAgent(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "yadlfs/1.2.3", r.Header.Get("User-Agent"))
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client := NewYandexDiskClient("token", "/project")
+	client.BaseURL = server.URL
+	client.HTTPClient = server.Client()
+	client.UserAgent = "yadlfs/1.2.3"
+
+	_, err := client.Stat(t.Context(), "oid")
+	require.ErrorIs(t, err, ErrResourceNotFound)
+}
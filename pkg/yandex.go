@@ -6,9 +6,13 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 )
 
 var (
@@ -19,18 +23,74 @@ var (
 	ErrRequestDownloadURL     = errors.New("failed to request download URL")
 	ErrDecodeDownloadResponse = errors.New("failed to decode download URL response")
 	ErrDownloadFile           = errors.New("failed to download file")
+	ErrListResources          = errors.New("failed to list resources")
+	ErrDecodeListResponse     = errors.New("failed to decode resources list response")
+	ErrDeleteResource         = errors.New("failed to delete resource")
+	ErrResourceNotFound       = errors.New("resource not found")
+	ErrStatResource           = errors.New("failed to get resource metadata")
+	ErrDecodeStatResponse     = errors.New("failed to decode resource metadata response")
+	ErrCopyResource           = errors.New("failed to copy resource")
+	ErrMoveResource           = errors.New("failed to move resource")
+	ErrRangesNotSupported     = errors.New("storage doesn't accept ranges")
+	ErrUploadURLExpired       = errors.New("upload URL expired")
+	ErrInsufficientStorage    = errors.New("insufficient storage")
+	ErrDiskInfo               = errors.New("failed to get disk info")
+	ErrDecodeDiskResponse     = errors.New("failed to decode disk info response")
+	ErrUnauthorized           = errors.New("token is invalid or expired")
+	ErrForbidden              = errors.New("token lacks the required scope")
 )
 
+const listPageSize = 1000
+
 type yandexDiskClientResponse struct {
 	Href   string `json:"href"`
 	Method string `json:"method"`
 }
 
+// Resource describes a file stored on Yandex Disk.
+type Resource struct {
+	Name     string    `json:"name"`
+	Path     string    `json:"path"`
+	Type     string    `json:"type"`
+	Size     int64     `json:"size"`
+	Created  time.Time `json:"created"`
+	Modified time.Time `json:"modified"`
+	MD5      string    `json:"md5,omitempty"`
+	SHA256   string    `json:"sha256,omitempty"`
+}
+
+// DiskInfo describes the space of the Yandex Disk account.
+type DiskInfo struct {
+	TotalSpace int64 `json:"total_space"`
+	UsedSpace  int64 `json:"used_space"`
+	TrashSize  int64 `json:"trash_size"`
+}
+
+// FreeSpace returns the space left for new files. Files in the trash count
+// as used until the trash is emptied.
+func (d DiskInfo) FreeSpace() int64 {
+	return max(d.TotalSpace-d.UsedSpace, 0)
+}
+
+type yandexDiskListResponse struct {
+	Embedded struct {
+		Items  []Resource `json:"items"`
+		Total  int        `json:"total"`
+		Limit  int        `json:"limit"`
+		Offset int        `json:"offset"`
+	} `json:"_embedded"`
+}
+
 // YandexDiskClient represents a client for interacting with Yandex Disk API.
 type YandexDiskClient struct {
 	OAuthToken string
 	DiskFolder string
 	BaseURL    string
+	Operations OperationBackoff
+	Logger     *slog.Logger
+	Observer   RequestObserver
+	HTTPClient *http.Client
+	UserAgent  string
 }
 
 // NewYandexDiskClient creates a new YandexDiskClient with the provided OAuth token.
@@ -39,103 +99,525 @@ func NewYandexDiskClient(oauthToken string, diskFolder string) *YandexDiskClient
 		OAuthToken: oauthToken,
 		DiskFolder: diskFolder,
 		BaseURL:    "https://cloud-api.yandex.net/v1/disk",
+		Operations: DefaultOperationBackoff,
+		Logger:     slog.New(slog.DiscardHandler),
+		HTTPClient: http.DefaultClient,
+		UserAgent:  "yadlfs",
+	}
+}
+
+// do sends the request and logs its duration and status. Only API URLs are
+// logged in full, the signed upload/download hrefs are reduced to the host.
+func (c *YandexDiskClient) do(req *http.Request) (*http.Response, error) {
+	logger := c.Logger
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+
+	endpoint := req.URL.Scheme + "://" + req.URL.Host
+	if strings.HasPrefix(req.URL.String(), c.BaseURL) {
+		endpoint += req.URL.Path
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+
+	start := time.Now()
+	resp, err := httpClient.Do(req)
+	duration := time.Since(start)
+	attrs := []any{"method", req.Method, "endpoint", endpoint, "duration", duration}
+
+	if err != nil {
+		c.observe(req, 0, duration)
+		logger.WarnContext(req.Context(), "http request failed", append(attrs, "error", err)...)
+
+		return nil, err //nolint:wrapcheck // wrapped by the callers
 	}
+
+	c.observe(req, resp.StatusCode, duration)
+
+	level := slog.LevelDebug
+	if resp.StatusCode >= http.StatusBadRequest {
+		level = slog.LevelWarn
+	}
+
+	logger.Log(req.Context(), level, "http request", append(attrs, "status", resp.StatusCode)...)
+
+	return resp, nil
+}
+
+// RequestObserver receives the outcome of every HTTP request made by the
+// client, status is 0 when no response was received.
+type RequestObserver interface {
+	ObserveRequest(method, endpoint string, status int, duration time.Duration)
+}
+
+func (c *YandexDiskClient) observe(req *http.Request, status int, duration time.Duration) {
+	if c.Observer == nil {
+		return
+	}
+
+	c.Observer.ObserveRequest(req.Method, c.endpointName(req), status, duration)
+}
+
+// endpointName returns a low cardinality name of the requested endpoint:
+// the API path, or "storage" for the signed upload/download hrefs.
+func (c *YandexDiskClient) endpointName(req *http.Request) string {
+	if !strings.HasPrefix(req.URL.String(), c.BaseURL) {
+		return "storage"
+	}
+
+	base, err := url.Parse(c.BaseURL)
+	if err != nil {
+		return req.URL.Path
+	}
+
+	endpoint := strings.TrimPrefix(req.URL.Path, base.Path)
+	if strings.HasPrefix(endpoint, "/operations/") {
+		return "/operations"
+	}
+
+	return endpoint
+}
+
+// ObjectPath returns the absolute disk path of a file in the project folder.
+func (c *YandexDiskClient) ObjectPath(name string) string {
+	return filepath.Join(c.DiskFolder, name)
 }
 
 // Upload uploads a file to Yandex Disk.
 func (c *YandexDiskClient) Upload(ctx context.Context, filePath string, file io.Reader, overwrite bool) error {
 	// Step 1: Request upload URL
-	filePath = filepath.Join(c.DiskFolder, filePath)
-	uploadURL := fmt.Sprintf("%s/resources/upload?path=%s&overwrite=%t", c.BaseURL, url.QueryEscape(filePath), overwrite)
+	uploadResponse, err := c.requestUpload(ctx, filePath, overwrite)
+	if err != nil {
+		return err
+	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uploadURL, nil)
+	// Step 2: Upload the file
+	req, err := http.NewRequestWithContext(ctx, uploadResponse.Method, uploadResponse.Href, file)
 	if err != nil {
 		return fmt.Errorf("%w: %w", ErrCreateRequest, err)
 	}
 
-	req.Header.Set("Authorization", "OAuth "+c.OAuthToken)
-
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
-		return fmt.Errorf("%w: %w", ErrRequestUploadURL, err)
+		return fmt.Errorf("%w: %w", ErrUploadFile, err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("%w: %s", ErrRequestUploadURL, resp.Status)
+	if resp.StatusCode == http.StatusInsufficientStorage {
+		return fmt.Errorf("%w: %w: %s", ErrUploadFile, ErrInsufficientStorage, resp.Status)
 	}
 
-	uploadResponse := yandexDiskClientResponse{}
+	if resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("%w: %s", ErrUploadFile, resp.Status)
+	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&uploadResponse); err != nil {
-		return fmt.Errorf("%w: %w", ErrDecodeUploadResponse, err)
+	return nil
+}
+
+// UploadURL requests an upload href for a file, which UploadRange sends the
+// file to in chunks.
+func (c *YandexDiskClient) UploadURL(ctx context.Context, filePath string, overwrite bool) (string, error) {
+	uploadResponse, err := c.requestUpload(ctx, filePath, overwrite)
+	if err != nil {
+		return "", err
 	}
 
-	// Step 2: Upload the file
-	req, err = http.NewRequestWithContext(ctx, uploadResponse.Method, uploadResponse.Href, file)
+	return uploadResponse.Href, nil
+}
+
+// UploadRange uploads length bytes of a file starting at offset to an upload
+// href. size is the file size or -1 while it's unknown. It fails with
+// ErrRangesNotSupported when the storage doesn't accept partial uploads and
+// with ErrUploadURLExpired when the href is no longer valid.
+func (c *YandexDiskClient) UploadRange(ctx context.Context, href string, r io.Reader, offset, length, size int64) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, href, r)
 	if err != nil {
 		return fmt.Errorf("%w: %w", ErrCreateRequest, err)
 	}
 
-	resp, err = http.DefaultClient.Do(req)
+	total := "*"
+	if size >= 0 {
+		total = strconv.FormatInt(size, 10)
+	}
+
+	req.ContentLength = length
+	req.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%s", offset, offset+length-1, total))
+
+	resp, err := c.do(req)
 	if err != nil {
 		return fmt.Errorf("%w: %w", ErrUploadFile, err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusCreated {
+	final := size >= 0 && offset+length >= size
+
+	switch resp.StatusCode {
+	case http.StatusCreated, http.StatusOK:
+		// the storage ignored the range and created the file out of a chunk
+		if !final {
+			return fmt.Errorf("%w: %s for a partial chunk", ErrRangesNotSupported, resp.Status)
+		}
+
+		return nil
+	case http.StatusAccepted, http.StatusNoContent, http.StatusPermanentRedirect:
+		return nil
+	case http.StatusNotFound, http.StatusGone:
+		return fmt.Errorf("%w: %s", ErrUploadURLExpired, resp.Status)
+	case http.StatusLengthRequired, http.StatusRequestedRangeNotSatisfiable, http.StatusNotImplemented:
+		return fmt.Errorf("%w: %s", ErrRangesNotSupported, resp.Status)
+	case http.StatusInsufficientStorage:
+		return fmt.Errorf("%w: %w: %s", ErrUploadFile, ErrInsufficientStorage, resp.Status)
+	default:
 		return fmt.Errorf("%w: %s", ErrUploadFile, resp.Status)
 	}
+}
+
+func (c *YandexDiskClient) requestUpload(
+	ctx context.Context,
+	filePath string,
+	overwrite bool,
+) (yandexDiskClientResponse, error) {
+	uploadResponse := yandexDiskClientResponse{}
+	filePath = filepath.Join(c.DiskFolder, filePath)
+	uploadURL := fmt.Sprintf("%s/resources/upload?path=%s&overwrite=%t", c.BaseURL, url.QueryEscape(filePath), overwrite)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uploadURL, nil)
+	if err != nil {
+		return uploadResponse, fmt.Errorf("%w: %w", ErrCreateRequest, err)
+	}
 
-	return nil
+	req.Header.Set("Authorization", "OAuth "+c.OAuthToken)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return uploadResponse, fmt.Errorf("%w: %w", ErrRequestUploadURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusInsufficientStorage {
+		return uploadResponse, fmt.Errorf("%w: %w: %s", ErrRequestUploadURL, ErrInsufficientStorage, resp.Status)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return uploadResponse, fmt.Errorf("%w: %s", ErrRequestUploadURL, resp.Status)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&uploadResponse); err != nil {
+		return uploadResponse, fmt.Errorf("%w: %w", ErrDecodeUploadResponse, err)
+	}
+
+	return uploadResponse, nil
 }
 
 // Download downloads a file from Yandex Disk.
 func (c *YandexDiskClient) Download(ctx context.Context, filePath string) (io.ReadCloser, error) {
 	// Step 1: Request download URL
+	downloadResponse, err := c.requestDownload(ctx, filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 2: Download the file
+	req, err := http.NewRequestWithContext(ctx, downloadResponse.Method, downloadResponse.Href, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrCreateRequest, err)
+	}
+
+	req.Header.Set("Authorization", "OAuth "+c.OAuthToken)
+
+	//nolint:bodyclose //resp.Body is io.ReadCloser, and will be closed by the caller
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDownloadFile, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+
+		return nil, fmt.Errorf("%w: %s", ErrDownloadFile, resp.Status)
+	}
+
+	return resp.Body, nil
+}
+
+// DownloadURL requests a download href for a file, which DownloadRange reads
+// the file from in parts.
+func (c *YandexDiskClient) DownloadURL(ctx context.Context, filePath string) (string, error) {
+	downloadResponse, err := c.requestDownload(ctx, filePath)
+	if err != nil {
+		return "", err
+	}
+
+	return downloadResponse.Href, nil
+}
+
+// DownloadRange downloads length bytes of a file starting at offset from a
+// download href. It fails with ErrRangesNotSupported when the storage
+// responds with the whole file.
+func (c *YandexDiskClient) DownloadRange(ctx context.Context, href string, offset, length int64) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, href, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrCreateRequest, err)
+	}
+
+	req.Header.Set("Authorization", "OAuth "+c.OAuthToken)
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+
+	//nolint:bodyclose //resp.Body is io.ReadCloser, and will be closed by the caller
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDownloadFile, err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		return resp.Body, nil
+	case http.StatusOK:
+		resp.Body.Close()
+
+		return nil, fmt.Errorf("%w: %s", ErrRangesNotSupported, resp.Status)
+	default:
+		resp.Body.Close()
+
+		return nil, fmt.Errorf("%w: %s", ErrDownloadFile, resp.Status)
+	}
+}
+
+func (c *YandexDiskClient) requestDownload(ctx context.Context, filePath string) (yandexDiskClientResponse, error) {
+	downloadResponse := yandexDiskClientResponse{}
 	filePath = filepath.Join(c.DiskFolder, filePath)
 	downloadURL := fmt.Sprintf("%s/resources/download?path=%s", c.BaseURL, url.QueryEscape(filePath))
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, downloadURL, nil)
+	if err != nil {
+		return downloadResponse, fmt.Errorf("%w: %w", ErrCreateRequest, err)
+	}
+
+	req.Header.Set("Authorization", "OAuth "+c.OAuthToken)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return downloadResponse, fmt.Errorf("%w: %w", ErrRequestDownloadURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return downloadResponse, fmt.Errorf("%w: %s", ErrRequestDownloadURL, resp.Status)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&downloadResponse); err != nil {
+		return downloadResponse, fmt.Errorf("%w: %w", ErrDecodeDownloadResponse, err)
+	}
+
+	return downloadResponse, nil
+}
+
+// Disk returns the space of the account the token belongs to.
+func (c *YandexDiskClient) Disk(ctx context.Context) (*DiskInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.BaseURL+"/", nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrCreateRequest, err)
+	}
+
+	req.Header.Set("Authorization", "OAuth "+c.OAuthToken)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDiskInfo, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized:
+		return nil, fmt.Errorf("%w: %w: %s", ErrDiskInfo, ErrUnauthorized, resp.Status)
+	case http.StatusForbidden:
+		return nil, fmt.Errorf("%w: %w: %s", ErrDiskInfo, ErrForbidden, resp.Status)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrDiskInfo, resp.Status)
+	}
+
+	info := &DiskInfo{}
+
+	if err := json.NewDecoder(resp.Body).Decode(info); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDecodeDiskResponse, err)
+	}
+
+	return info, nil
+}
+
+// List lists all files stored in the project folder, following the pagination.
+func (c *YandexDiskClient) List(ctx context.Context) ([]Resource, error) {
+	var resources []Resource
+
+	for offset := 0; ; offset += listPageSize {
+		page, err := c.listPage(ctx, offset)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range page.Embedded.Items {
+			if item.Type == "file" {
+				resources = append(resources, item)
+			}
+		}
+
+		if len(page.Embedded.Items) < listPageSize || offset+listPageSize >= page.Embedded.Total {
+			return resources, nil
+		}
+	}
+}
+
+func (c *YandexDiskClient) listPage(ctx context.Context, offset int) (*yandexDiskListResponse, error) {
+	listURL := fmt.Sprintf(
+		"%s/resources?path=%s&limit=%d&offset=%d&sort=name",
+		c.BaseURL, url.QueryEscape(c.DiskFolder), listPageSize, offset,
+	)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, listURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %w", ErrCreateRequest, err)
 	}
 
 	req.Header.Set("Authorization", "OAuth "+c.OAuthToken)
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %w", ErrRequestDownloadURL, err)
+		return nil, fmt.Errorf("%w: %w", ErrListResources, err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("%w: %s", ErrRequestDownloadURL, resp.Status)
+		return nil, fmt.Errorf("%w: %s", ErrListResources, resp.Status)
 	}
 
-	downloadResponse := yandexDiskClientResponse{}
+	page := &yandexDiskListResponse{}
 
-	if err := json.NewDecoder(resp.Body).Decode(&downloadResponse); err != nil {
-		return nil, fmt.Errorf("%w: %w", ErrDecodeDownloadResponse, err)
+	if err := json.NewDecoder(resp.Body).Decode(page); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDecodeListResponse, err)
 	}
 
-	// Step 2: Download the file
-	req, err = http.NewRequestWithContext(ctx, downloadResponse.Method, downloadResponse.Href, nil)
+	return page, nil
+}
+
+// Delete deletes a file from the project folder. Unless permanently is set,
+// the file is moved to the Yandex Disk trash.
+func (c *YandexDiskClient) Delete(ctx context.Context, filePath string, permanently bool) error {
+	filePath = filepath.Join(c.DiskFolder, filePath)
+	deleteURL := fmt.Sprintf("%s/resources?path=%s&permanently=%t", c.BaseURL, url.QueryEscape(filePath), permanently)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, deleteURL, nil)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrCreateRequest, err)
+	}
+
+	req.Header.Set("Authorization", "OAuth "+c.OAuthToken)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrDeleteResource, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNoContent:
+		return nil
+	case http.StatusAccepted:
+		if err := c.waitAccepted(ctx, resp); err != nil {
+			return fmt.Errorf("%w: %w", ErrDeleteResource, err)
+		}
+
+		return nil
+	case http.StatusNotFound:
+		return fmt.Errorf("%w: %w: %s", ErrDeleteResource, ErrResourceNotFound, filePath)
+	default:
+		return fmt.Errorf("%w: %s", ErrDeleteResource, resp.Status)
+	}
+}
+
+// Stat returns the metadata of a file in the project folder.
+func (c *YandexDiskClient) Stat(ctx context.Context, filePath string) (*Resource, error) {
+	filePath = filepath.Join(c.DiskFolder, filePath)
+	statURL := fmt.Sprintf("%s/resources?path=%s", c.BaseURL, url.QueryEscape(filePath))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, statURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %w", ErrCreateRequest, err)
 	}
 
 	req.Header.Set("Authorization", "OAuth "+c.OAuthToken)
 
-	//nolint:bodyclose //resp.Body is io.ReadCloser, and will be closed by the caller
-	resp, err = http.DefaultClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %w", ErrDownloadFile, err)
+		return nil, fmt.Errorf("%w: %w", ErrStatResource, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s", ErrResourceNotFound, filePath)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("%w: %s", ErrDownloadFile, resp.Status)
+		return nil, fmt.Errorf("%w: %s", ErrStatResource, resp.Status)
 	}
 
-	return resp.Body, nil
+	resource := &Resource{}
+
+	if err := json.NewDecoder(resp.Body).Decode(resource); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDecodeStatResponse, err)
+	}
+
+	return resource, nil
+}
+
+// Copy copies a file on the server side. Paths are absolute disk paths, so
+// objects can be copied between project folders of the same account.
+func (c *YandexDiskClient) Copy(ctx context.Context, from, to string, overwrite bool) error {
+	return c.relocate(ctx, "copy", from, to, overwrite, ErrCopyResource)
+}
+
+// Move moves a file on the server side. Paths are absolute disk paths.
+func (c *YandexDiskClient) Move(ctx context.Context, from, to string, overwrite bool) error {
+	return c.relocate(ctx, "move", from, to, overwrite, ErrMoveResource)
+}
+
+func (c *YandexDiskClient) relocate(ctx context.Context, action, from, to string, overwrite bool, errKind error) error {
+	relocateURL := fmt.Sprintf(
+		"%s/resources/%s?from=%s&path=%s&overwrite=%t",
+		c.BaseURL, action, url.QueryEscape(from), url.QueryEscape(to), overwrite,
+	)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, relocateURL, nil)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrCreateRequest, err)
+	}
+
+	req.Header.Set("Authorization", "OAuth "+c.OAuthToken)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errKind, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusCreated:
+		return nil
+	case http.StatusAccepted:
+		if err := c.waitAccepted(ctx, resp); err != nil {
+			return fmt.Errorf("%w: %w", errKind, err)
+		}
+
+		return nil
+	default:
+		return fmt.Errorf("%w: %s", errKind, resp.Status)
+	}
 }
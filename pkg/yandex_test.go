@@ -0,0 +1,235 @@
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestYandexDiskClientList(t *testing.T) {
+	t.Parallel()
+
+	total := listPageSize + 5
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "OAuth token", r.Header.Get("Authorization"))
+		require.Equal(t, "/project", r.URL.Query().Get("path"))
+
+		offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+		require.NoError(t, err)
+
+		response := yandexDiskListResponse{}
+		response.Embedded.Total = total
+		response.Embedded.Offset = offset
+
+		for i := offset; i < min(offset+listPageSize, total); i++ {
+			resourceType := "file"
+			if i == 0 {
+				resourceType = "dir"
+			}
+
+			response.Embedded.Items = append(response.Embedded.Items, Resource{Name: fmt.Sprintf("oid%d", i), Type: resourceType})
+		}
+
+		require.NoError(t, json.NewEncoder(w).Encode(response))
+	}))
+	defer server.Close()
+
+	client := NewYandexDiskClient("token", "/project")
+	client.BaseURL = server.URL
+
+	resources, err := client.List(t.Context())
+	require.NoError(t, err)
+	require.Len(t, resources, total-1)
+	require.Equal(t, "oid1", resources[0].Name)
+	require.Equal(t, fmt.Sprintf("oid%d", total-1), resources[len(resources)-1].Name)
+}
+
+func TestYandexDiskClientStat(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("path") != "/project/present" {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		require.NoError(t, json.NewEncoder(w).Encode(Resource{Name: "present", Type: "file", Size: 42, SHA256: "abc"}))
+	}))
+	defer server.Close()
+
+	client := NewYandexDiskClient("token", "/project")
+	client.BaseURL = server.URL
+
+	resource, err := client.Stat(t.Context(), "present")
+	require.NoError(t, err)
+	require.Equal(t, int64(42), resource.Size)
+	require.Equal(t, "abc", resource.SHA256)
+
+	_, err = client.Stat(t.Context(), "missing")
+	require.ErrorIs(t, err, ErrResourceNotFound)
+}
+
+func TestYandexDiskClientCopy(t *testing.T) {
+	t.Parallel()
+
+	var server *httptest.Server
+
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/resources/copy":
+			require.Equal(t, http.MethodPost, r.Method)
+			require.Equal(t, "/fork/oid", r.URL.Query().Get("path"))
+
+			switch r.URL.Query().Get("from") {
+			case "/project/small":
+				w.WriteHeader(http.StatusCreated)
+			default:
+				w.WriteHeader(http.StatusAccepted)
+				require.NoError(t, json.NewEncoder(w).Encode(yandexDiskClientResponse{
+					Href:   server.URL + "/operations/" + strings.TrimPrefix(r.URL.Query().Get("from"), "/project/"),
+					Method: http.MethodGet,
+				}))
+			}
+		case "/operations/large":
+			require.NoError(t, json.NewEncoder(w).Encode(yandexDiskOperationResponse{Status: "success"}))
+		case "/operations/broken":
+			require.NoError(t, json.NewEncoder(w).Encode(yandexDiskOperationResponse{Status: "failed"}))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := NewYandexDiskClient("token", "/project")
+	client.BaseURL = server.URL
+
+	require.NoError(t, client.Copy(t.Context(), "/project/small", "/fork/oid", true))
+	require.NoError(t, client.Copy(t.Context(), "/project/large", "/fork/oid", true))
+	require.ErrorIs(t, client.Copy(t.Context(), "/project/broken", "/fork/oid", true), ErrOperationFailed)
+}
+
+func TestYandexDiskClientUploadRange(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/accepted":
+			require.Equal(t, http.MethodPut, r.Method)
+			require.Equal(t, "bytes 0-3/*", r.Header.Get("Content-Range"))
+			require.Equal(t, int64(4), r.ContentLength)
+			w.WriteHeader(http.StatusAccepted)
+		case "/created":
+			require.Equal(t, "bytes 4-7/8", r.Header.Get("Content-Range"))
+			w.WriteHeader(http.StatusCreated)
+		case "/ignored":
+			w.WriteHeader(http.StatusCreated)
+		case "/expired":
+			w.WriteHeader(http.StatusGone)
+		default:
+			w.WriteHeader(http.StatusNotImplemented)
+		}
+	}))
+	defer server.Close()
+
+	client := NewYandexDiskClient("token", "/project")
+
+	upload := func(path string, offset, size int64) error {
+		return client.UploadRange(t.Context(), server.URL+path, strings.NewReader("data"), offset, 4, size)
+	}
+
+	require.NoError(t, upload("/accepted", 0, -1))
+	require.NoError(t, upload("/created", 4, 8))
+	require.ErrorIs(t, upload("/ignored", 0, -1), ErrRangesNotSupported)
+	require.ErrorIs(t, upload("/expired", 4, -1), ErrUploadURLExpired)
+	require.ErrorIs(t, upload("/unsupported", 0, -1), ErrRangesNotSupported)
+}
+
+func TestYandexDiskClientDownloadRange(t *testing.T) {
+	t.Parallel()
+
+	content := "0123456789"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/whole" {
+			_, _ = w.Write([]byte(content))
+
+			return
+		}
+
+		require.Equal(t, "bytes=2-5", r.Header.Get("Range"))
+		http.ServeContent(w, r, "object", time.Time{}, strings.NewReader(content))
+	}))
+	defer server.Close()
+
+	client := NewYandexDiskClient("token", "/project")
+
+	body, err := client.DownloadRange(t.Context(), server.URL+"/ranged", 2, 4)
+	require.NoError(t, err)
+
+	data, err := io.ReadAll(body)
+	require.NoError(t, err)
+	require.NoError(t, body.Close())
+	require.Equal(t, "2345", string(data))
+
+	_, err = client.DownloadRange(t.Context(), server.URL+"/whole", 2, 4)
+	require.ErrorIs(t, err, ErrRangesNotSupported)
+}
+
+func TestYandexDiskClientDisk(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			_, _ = w.Write([]byte(`{"total_space": 1000, "used_space": 400, "trash_size": 100}`))
+		case "/resources/upload":
+			w.WriteHeader(http.StatusInsufficientStorage)
+		}
+	}))
+	defer server.Close()
+
+	client := NewYandexDiskClient("token", "/project")
+	client.BaseURL = server.URL
+
+	info, err := client.Disk(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, DiskInfo{TotalSpace: 1000, UsedSpace: 400, TrashSize: 100}, *info)
+	require.Equal(t, int64(600), info.FreeSpace())
+
+	err = client.Upload(t.Context(), "oid", strings.NewReader("data"), true)
+	require.ErrorIs(t, err, ErrInsufficientStorage)
+}
+
+func TestYandexDiskClientDiskUnauthorized(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "OAuth narrow" {
+			w.WriteHeader(http.StatusForbidden)
+		} else {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	client := NewYandexDiskClient("expired", "/project")
+	client.BaseURL = server.URL
+
+	_, err := client.Disk(t.Context())
+	require.ErrorIs(t, err, ErrUnauthorized)
+
+	client.OAuthToken = "narrow"
+
+	_, err = client.Disk(t.Context())
+	require.ErrorIs(t, err, ErrForbidden)
+}